@@ -0,0 +1,175 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// Anonymity levels reported by checkAnonymity
+const (
+    AnonymityTransparent = "transparent" // leaks the client IP
+    AnonymityAnonymous   = "anonymous"   // hides the client IP but reveals it is a proxy
+    AnonymityElite       = "elite"       // looks like a direct connection
+)
+
+// proxyHeaders are request headers that proxies add to identify themselves
+// or the client they forward for
+var proxyHeaders = []string{
+    "Via",
+    "X-Forwarded-For",
+    "Forwarded",
+    "X-Real-Ip",
+    "X-Proxy-Id",
+    "Proxy-Connection",
+    "Client-Ip",
+    "X-Client-Ip",
+}
+
+// publicIP returns this machine's public IP as seen by an IP-echo service
+func publicIP(ctx context.Context) (string, error) {
+    client := &http.Client{Timeout: 10 * time.Second}
+    return echoedIP(ctx, client, "https://api.ipify.org")
+}
+
+// echoedIP requests a plain-text IP-echo service such as api.ipify.org with
+// client and returns the IP it reports
+func echoedIP(ctx context.Context, client *http.Client, echoURL string) (string, error) {
+    req, err := http.NewRequestWithContext(ctx, "GET", echoURL, nil)
+    if err != nil {
+        return "", err
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("status code: %d", resp.StatusCode)
+    }
+
+    body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+    if err != nil {
+        return "", err
+    }
+
+    ip := strings.TrimSpace(string(body))
+    if net.ParseIP(ip) == nil {
+        return "", fmt.Errorf("unexpected response from IP echo service: %q", ip)
+    }
+    return ip, nil
+}
+
+// checkLeak requests the IP-echo service at leakCheckURL through the proxy
+// and reports whether the IP seen by the service is this machine's own
+func (pf *ProxyFetcher) checkLeak(ctx context.Context, proxy string, info ProxyInfo) (bool, error) {
+    client, err := pf.proxyClient(proxy, info, pf.checkTimeout)
+    if err != nil {
+        return false, err
+    }
+    defer client.CloseIdleConnections()
+
+    ip, err := echoedIP(ctx, client, pf.leakCheckURL)
+    if err != nil {
+        return false, err
+    }
+    return net.ParseIP(ip).Equal(net.ParseIP(pf.realIP)), nil
+}
+
+// checkAnonymity requests the header-echo endpoint (httpbin /headers format)
+// through the proxy and classifies it by the headers that reached the server.
+// Proxy judges take its place when judgeURLs are configured.
+func (pf *ProxyFetcher) checkAnonymity(ctx context.Context, proxy string, info ProxyInfo) (string, error) {
+    if len(pf.judgeURLs) > 0 {
+        return pf.judgeAnonymity(ctx, proxy, info)
+    }
+
+    client, err := pf.proxyClient(proxy, info, pf.checkTimeout)
+    if err != nil {
+        return "", err
+    }
+    defer client.CloseIdleConnections()
+
+    req, err := http.NewRequestWithContext(ctx, "GET", pf.anonymityURL, nil)
+    if err != nil {
+        return "", err
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("status code: %d", resp.StatusCode)
+    }
+
+    var echo struct {
+        Headers map[string]string `json:"headers"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&echo); err != nil {
+        return "", err
+    }
+
+    return classifyAnonymity(echo.Headers, pf.realIP), nil
+}
+
+// classifyAnonymity derives the anonymity level from the echoed headers
+func classifyAnonymity(headers map[string]string, realIP string) string {
+    canonical := make(map[string]string, len(headers))
+    for k, v := range headers {
+        canonical[http.CanonicalHeaderKey(k)] = v
+    }
+
+    if ip := net.ParseIP(realIP); ip != nil {
+        for _, v := range canonical {
+            if headerHasIP(v, ip) {
+                return AnonymityTransparent
+            }
+        }
+    }
+
+    for _, h := range proxyHeaders {
+        if _, ok := canonical[h]; ok {
+            return AnonymityAnonymous
+        }
+    }
+    return AnonymityElite
+}
+
+// headerHasIP reports whether ip is one of the addresses listed in a header
+// value such as "1.2.3.4, 5.6.7.8" or "for=1.2.3.4:80", compared exactly so
+// 1.2.3.4 does not match 11.2.3.45
+func headerHasIP(value string, ip net.IP) bool {
+    fields := strings.FieldsFunc(value, func(r rune) bool {
+        return r == ',' || r == ' ' || r == ';' || r == '=' || r == '"'
+    })
+    for _, field := range fields {
+        if host, _, err := net.SplitHostPort(field); err == nil {
+            field = host
+        }
+        if ip.Equal(net.ParseIP(strings.Trim(field, "[]"))) {
+            return true
+        }
+    }
+    return false
+}
+
+// filterLeaking drops the proxies that failed the leak check
+func filterLeaking(proxies []ProxyResult) []ProxyResult {
+    var filtered []ProxyResult
+    for _, proxy := range proxies {
+        if !proxy.Leaking {
+            filtered = append(filtered, proxy)
+        }
+    }
+    return filtered
+}
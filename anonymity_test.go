@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestClassifyAnonymity(t *testing.T) {
+    const realIP = "1.2.3.4"
+    tests := []struct {
+        name    string
+        headers map[string]string
+        want    string
+    }{
+        {name: "no proxy headers", headers: map[string]string{"Host": "example.com"}, want: AnonymityElite},
+        {name: "real ip", headers: map[string]string{"X-Forwarded-For": "1.2.3.4"}, want: AnonymityTransparent},
+        {name: "real ip in list", headers: map[string]string{"x-forwarded-for": "10.0.0.1, 1.2.3.4"}, want: AnonymityTransparent},
+        {name: "forwarded with port", headers: map[string]string{"Forwarded": "for=1.2.3.4:5000;proto=http"}, want: AnonymityTransparent},
+        {name: "similar ip", headers: map[string]string{"X-Forwarded-For": "11.2.3.45"}, want: AnonymityAnonymous},
+        {name: "ip as substring", headers: map[string]string{"Via": "1.1 proxy-1.2.3.4.example.com"}, want: AnonymityAnonymous},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := classifyAnonymity(tt.headers, realIP); got != tt.want {
+                t.Errorf("classifyAnonymity(%v) = %q, want %q", tt.headers, got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+    "net"
+    "strconv"
+    "strings"
+
+    "github.com/oschwald/geoip2-golang"
+)
+
+// lookupASNs sets the autonomous system number and organization of each
+// proxy using the MaxMind GeoLite2 ASN database at path. Proxies whose IP
+// has no entry keep an ASN of 0 and an empty organization.
+func lookupASNs(path string, proxies []ProxyResult) error {
+    db, err := geoip2.Open(path)
+    if err != nil {
+        return err
+    }
+    defer db.Close()
+
+    for i := range proxies {
+        host, _, err := net.SplitHostPort(proxies[i].Proxy)
+        if err != nil {
+            continue
+        }
+        ip := net.ParseIP(host)
+        if ip == nil {
+            continue
+        }
+        record, err := db.ASN(ip)
+        if err != nil {
+            continue
+        }
+        proxies[i].ASN = record.AutonomousSystemNumber
+        proxies[i].Org = record.AutonomousSystemOrganization
+    }
+    return nil
+}
+
+// asnMatcher matches proxies by AS number ("13335" or "AS13335") or by a
+// case-insensitive substring of their organization ("amazon")
+type asnMatcher struct {
+    numbers map[uint]bool
+    orgs    []string
+}
+
+func newASNMatcher(entries []string) asnMatcher {
+    m := asnMatcher{numbers: make(map[uint]bool)}
+    for _, entry := range entries {
+        entry = strings.TrimSpace(entry)
+        digits := strings.TrimPrefix(strings.ToUpper(entry), "AS")
+        if n, err := strconv.ParseUint(digits, 10, 32); err == nil {
+            m.numbers[uint(n)] = true
+        } else if entry != "" {
+            m.orgs = append(m.orgs, strings.ToLower(entry))
+        }
+    }
+    return m
+}
+
+func (m asnMatcher) matches(proxy ProxyResult) bool {
+    if proxy.ASN != 0 && m.numbers[proxy.ASN] {
+        return true
+    }
+    org := strings.ToLower(proxy.Org)
+    for _, o := range m.orgs {
+        if org != "" && strings.Contains(org, o) {
+            return true
+        }
+    }
+    return false
+}
+
+// filterASNs keeps the proxies matching allow, when it is not empty, and
+// drops those matching deny. Proxies without ASN data never match, so they
+// fail an allow list but pass a deny list.
+func filterASNs(proxies []ProxyResult, allow, deny []string) []ProxyResult {
+    allowed := newASNMatcher(allow)
+    denied := newASNMatcher(deny)
+
+    var filtered []ProxyResult
+    for _, proxy := range proxies {
+        if len(allow) > 0 && !allowed.matches(proxy) {
+            continue
+        }
+        if denied.matches(proxy) {
+            continue
+        }
+        filtered = append(filtered, proxy)
+    }
+
+    infof("ASN filter kept %d of %d working proxies", len(filtered), len(proxies))
+    return filtered
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "net"
+    "os"
+    "strings"
+)
+
+// blocklist is a list of networks whose proxies are dropped when stored
+type blocklist []*net.IPNet
+
+// loadBlocklist reads a file of CIDRs such as 10.0.0.0/8, one per line.
+// Bare IPs block that single address; blank lines and # comments are
+// skipped.
+func loadBlocklist(path string) (blocklist, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    var list blocklist
+    scanner := bufio.NewScanner(file)
+    for n := 1; scanner.Scan(); n++ {
+        line := strings.TrimSpace(scanner.Text())
+        if i := strings.Index(line, "#"); i != -1 {
+            line = strings.TrimSpace(line[:i])
+        }
+        if line == "" {
+            continue
+        }
+        if !strings.Contains(line, "/") {
+            ip := net.ParseIP(line)
+            if ip == nil {
+                return nil, fmt.Errorf("line %d: invalid IP %q", n, line)
+            }
+            bits := 128
+            if ip.To4() != nil {
+                ip, bits = ip.To4(), 32
+            }
+            list = append(list, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+            continue
+        }
+        _, network, err := net.ParseCIDR(line)
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %v", n, err)
+        }
+        list = append(list, network)
+    }
+    return list, scanner.Err()
+}
+
+// blocks reports whether the host of a host:port address lies in one of the
+// networks. Hostnames are never blocked since they are not resolved.
+func (b blocklist) blocks(address string) bool {
+    if len(b) == 0 {
+        return false
+    }
+    host, _, err := net.SplitHostPort(address)
+    if err != nil {
+        return false
+    }
+    ip := net.ParseIP(host)
+    if ip == nil {
+        return false
+    }
+    for _, network := range b {
+        if network.Contains(ip) {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+    "fmt"
+    "os"
+)
+
+// proxyChanges is the difference between the previous and current output
+type proxyChanges struct {
+    Added   []ProxyResult
+    Dropped []string // addresses listed in the previous output only
+}
+
+// readPreviousProxies returns the addresses listed in an output file written
+// by an earlier run, such as proxies.txt
+func readPreviousProxies(path string) ([]string, error) {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    proxies, err := parsePlainHostPort(string(content), Source{URL: path, Protocol: "http"})
+    if err != nil {
+        return nil, err
+    }
+    addresses := make([]string, 0, len(proxies))
+    for _, proxy := range proxies {
+        addresses = append(addresses, proxy.Proxy)
+    }
+    return addresses, nil
+}
+
+// diffProxies compares the working proxies with the addresses of the
+// previous output
+func diffProxies(previous []string, current []ProxyResult) proxyChanges {
+    var changes proxyChanges
+    seen := make(map[string]bool, len(previous))
+    for _, address := range previous {
+        seen[address] = true
+    }
+    kept := make(map[string]bool, len(current))
+    for _, proxy := range current {
+        kept[proxy.Proxy] = true
+        if !seen[proxy.Proxy] {
+            changes.Added = append(changes.Added, proxy)
+        }
+    }
+    for _, address := range previous {
+        if !kept[address] {
+            changes.Dropped = append(changes.Dropped, address)
+            kept[address] = true // list duplicates once
+        }
+    }
+    return changes
+}
+
+// lines formats the changes as "newly added" and "dropped" sections to
+// append to a notification
+func (c proxyChanges) lines() []string {
+    lines := []string{"", fmt.Sprintf("# Newly added: %d", len(c.Added))}
+    for _, proxy := range c.Added {
+        if line, ok := proxychainsLine(proxy, false); ok {
+            lines = append(lines, line)
+        }
+    }
+    lines = append(lines, "", fmt.Sprintf("# Dropped: %d", len(c.Dropped)))
+    lines = append(lines, c.Dropped...)
+    return lines
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "os"
+    "time"
+)
+
+// Config is the optional JSON configuration file passed with -config
+type Config struct {
+    Sources   []Source          `json:"sources"`
+    UserAgent string            `json:"user_agent"` // overridden by -user-agent
+    Headers   map[string]string `json:"headers"`    // sent to every source, -header takes precedence
+    // Geonode adjusts the built-in geonode source; flags take precedence
+    Geonode *GeonodeQuery `json:"geonode"`
+}
+
+// Duration is a time.Duration read from a JSON string such as "30s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+    var s string
+    if err := json.Unmarshal(data, &s); err != nil {
+        return fmt.Errorf("duration must be a string like \"30s\"")
+    }
+    v, err := time.ParseDuration(s)
+    if err != nil {
+        return err
+    }
+    *d = Duration(v)
+    return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+    return json.Marshal(time.Duration(d).String())
+}
+
+// loadConfig reads the config file at path. A missing file is not an error:
+// it returns a nil config so the built-in defaults stay in effect.
+func loadConfig(path string) (*Config, error) {
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        infof("Config file %s not found, using built-in defaults", path)
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var cfg Config
+    if err := json.Unmarshal(data, &cfg); err != nil {
+        return nil, fmt.Errorf("invalid JSON: %v", err)
+    }
+
+    for i, source := range cfg.Sources {
+        if source.URL == "" {
+            return nil, fmt.Errorf("source %d has no url", i)
+        }
+        if source.Protocol == "" {
+            cfg.Sources[i].Protocol = "http"
+        }
+        if _, ok := parsers[source.parser()]; !ok {
+            return nil, fmt.Errorf("source %s has unknown parser %q", source.URL, source.Parser)
+        }
+        if source.parser() == "generic-json" && source.Fields == nil {
+            return nil, fmt.Errorf("source %s uses generic-json but has no fields", source.URL)
+        }
+    }
+
+    return &cfg, nil
+}
+
+// applyConfig overrides the fetcher defaults with the values set in cfg
+func (pf *ProxyFetcher) applyConfig(cfg *Config) {
+    if len(cfg.Sources) > 0 {
+        pf.sources = cfg.Sources
+        infof("Loaded %d sources from config", len(cfg.Sources))
+    }
+    if cfg.Geonode != nil {
+        pf.setGeonodeQuery(pf.geonodeQuery.merge(*cfg.Geonode))
+    }
+    if cfg.UserAgent != "" {
+        pf.userAgent = cfg.UserAgent
+    }
+    if len(cfg.Headers) > 0 {
+        pf.fetchHeaders = http.Header{}
+        for name, value := range cfg.Headers {
+            pf.fetchHeaders.Set(name, value)
+        }
+    }
+}
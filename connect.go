@@ -0,0 +1,118 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/base64"
+    "fmt"
+    "net"
+    "net/http"
+    "net/url"
+    "time"
+
+    netproxy "golang.org/x/net/proxy"
+)
+
+func init() {
+    netproxy.RegisterDialerType("http", newHTTPConnectDialer)
+}
+
+// httpConnectDialer opens tunnels through an HTTP proxy with CONNECT, so an
+// HTTP proxy can serve as the upstream hop of a chain
+type httpConnectDialer struct {
+    proxyAddr string
+    auth      string // Proxy-Authorization value, empty without credentials
+    forward   netproxy.Dialer
+}
+
+func newHTTPConnectDialer(u *url.URL, forward netproxy.Dialer) (netproxy.Dialer, error) {
+    d := &httpConnectDialer{proxyAddr: u.Host, forward: forward}
+    if u.User != nil {
+        password, _ := u.User.Password()
+        credentials := u.User.Username() + ":" + password
+        d.auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+    }
+    return d, nil
+}
+
+func (d *httpConnectDialer) Dial(network, addr string) (net.Conn, error) {
+    return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+    var conn net.Conn
+    var err error
+    if cd, ok := d.forward.(netproxy.ContextDialer); ok {
+        conn, err = cd.DialContext(ctx, "tcp", d.proxyAddr)
+    } else {
+        conn, err = d.forward.Dial("tcp", d.proxyAddr)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+        defer conn.SetDeadline(time.Time{})
+    }
+
+    req := &http.Request{
+        Method: http.MethodConnect,
+        URL:    &url.URL{Opaque: addr},
+        Host:   addr,
+        Header: make(http.Header),
+    }
+    if d.auth != "" {
+        req.Header.Set("Proxy-Authorization", d.auth)
+    }
+    if err := req.Write(conn); err != nil {
+        conn.Close()
+        return nil, err
+    }
+
+    br := bufio.NewReader(conn)
+    resp, err := http.ReadResponse(br, req)
+    if err != nil {
+        conn.Close()
+        return nil, err
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        conn.Close()
+        return nil, fmt.Errorf("http connect: %s", resp.Status)
+    }
+
+    if br.Buffered() > 0 {
+        return &bufferedConn{Conn: conn, r: br}, nil
+    }
+    return conn, nil
+}
+
+// bufferedConn reads through the bufio.Reader that consumed the CONNECT
+// response, so bytes the proxy sent right after it are not lost
+type bufferedConn struct {
+    net.Conn
+    r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+    return c.r.Read(p)
+}
+
+// connectTimeoutDialer caps how long connecting to a proxy may take,
+// separately from the timeout of the whole request, so proxies that do not
+// accept connections at all fail fast
+type connectTimeoutDialer struct {
+    dialer  netproxy.ContextDialer
+    timeout time.Duration
+}
+
+func (d connectTimeoutDialer) Dial(network, addr string) (net.Conn, error) {
+    return d.DialContext(context.Background(), network, addr)
+}
+
+func (d connectTimeoutDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+    ctx, cancel := context.WithTimeout(ctx, d.timeout)
+    defer cancel()
+    return d.dialer.DialContext(ctx, network, addr)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+    "bytes"
+    "compress/flate"
+    "compress/gzip"
+    "compress/zlib"
+    "fmt"
+    "io"
+    "strings"
+)
+
+// acceptEncoding is advertised to sources, whose responses are decoded by
+// decodeBody
+const acceptEncoding = "gzip, deflate"
+
+// decodeBody decompresses a response body according to its Content-Encoding.
+// Bodies starting with the gzip magic number are gunzipped even without the
+// header, since some sources compress without saying so.
+func decodeBody(encoding string, body []byte) ([]byte, error) {
+    encoding = strings.ToLower(strings.TrimSpace(encoding))
+    if encoding == "" && bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+        encoding = "gzip"
+    }
+
+    var r io.Reader
+    switch encoding {
+    case "", "identity":
+        return body, nil
+    case "gzip", "x-gzip":
+        zr, err := gzip.NewReader(bytes.NewReader(body))
+        if err != nil {
+            return nil, err
+        }
+        defer zr.Close()
+        r = zr
+    case "deflate":
+        // deflate is meant to be zlib-wrapped, but some servers send raw
+        // deflate data instead
+        zr, err := zlib.NewReader(bytes.NewReader(body))
+        if err != nil {
+            r = flate.NewReader(bytes.NewReader(body))
+        } else {
+            defer zr.Close()
+            r = zr
+        }
+    default:
+        return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+    }
+    return io.ReadAll(r)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// fixtureTime is the fixed clock of fixture runs, so their outputs are
+// identical from one run to the next
+var fixtureTime = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// useFixtures switches the fetcher to an offline run: the files in dir are
+// the only sources, proxies pass a mocked check and the clock is fixed
+func (pf *ProxyFetcher) useFixtures(dir string) error {
+    sources, err := loadFixtures(dir)
+    if err != nil {
+        return err
+    }
+    abs, err := filepath.Abs(dir)
+    if err != nil {
+        return err
+    }
+
+    // Only this client serves file:// URLs, rooted at the fixtures
+    // directory, so a live source cannot redirect to local files
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    transport.RegisterProtocol("file", http.NewFileTransport(http.Dir(abs)))
+    pf.httpClient = &http.Client{Transport: transport}
+    pf.sources = sources
+    pf.transportFunc = mockCheckTransport
+    pf.now = func() time.Time { return fixtureTime }
+    pf.noTelegram = true
+    pf.hostLimiter = nil // local files need no rate limit
+    return nil
+}
+
+// loadFixtures returns a file:/// source for every file in dir, in name
+// order, with URLs relative to dir. A file named after a protocol,
+// optionally followed by "-" or "." (socks5.txt, socks4-extra.txt), lists
+// proxies of that protocol; any other file lists http proxies.
+func loadFixtures(dir string) ([]Source, error) {
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return nil, err
+    }
+
+    var sources []Source
+    for _, entry := range entries {
+        if entry.IsDir() {
+            continue
+        }
+        protocol, _, _ := strings.Cut(strings.SplitN(entry.Name(), ".", 2)[0], "-")
+        if !knownProtocol(protocol) {
+            protocol = "http"
+        }
+        sources = append(sources, Source{
+            URL:      "file:///" + entry.Name(),
+            Protocol: protocol,
+        })
+    }
+    if len(sources) == 0 {
+        return nil, fmt.Errorf("no fixture files in %s", dir)
+    }
+    return sources, nil
+}
+
+// mockCheckTransport answers every request made through a proxy with an
+// empty 200 response without touching the network, so fixture runs are
+// offline and every listed proxy passes the basic check
+func mockCheckTransport(proxy string, info ProxyInfo) (http.RoundTripper, error) {
+    return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+        return &http.Response{
+            Status:     "200 OK",
+            StatusCode: http.StatusOK,
+            Proto:      "HTTP/1.1",
+            ProtoMajor: 1,
+            ProtoMinor: 1,
+            Header:     http.Header{"Content-Type": {"text/plain"}},
+            Body:       io.NopCloser(strings.NewReader("")),
+            Request:    req,
+        }, nil
+    }), nil
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+    return f(req)
+}
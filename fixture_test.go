@@ -0,0 +1,209 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "flag"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// TestFixtureRunGolden fetches, checks and saves the proxies of
+// testdata/fixtures offline and compares every output with its golden file
+func TestFixtureRunGolden(t *testing.T) {
+    t.Setenv("DISCORD_WEBHOOK", "")
+    t.Setenv("SLACK_WEBHOOK_URL", "")
+
+    pf := NewProxyFetcher()
+    if err := pf.useFixtures("testdata/fixtures"); err != nil {
+        t.Fatal(err)
+    }
+    pf.outDir = t.TempDir()
+    for _, format := range outputFormats {
+        pf.formats[format] = true
+    }
+
+    ctx := context.Background()
+    if err := pf.fetchAllProxies(ctx); err != nil {
+        t.Fatal(err)
+    }
+    proxies := pf.checkAndFilterProxies(ctx, ctx)
+    pf.scoreProxies(proxies)
+    pf.saveProxies(proxies)
+
+    for _, name := range []string{
+        "proxychains.conf",
+        "proxies.txt",
+        "proxies.json",
+        "proxies.csv",
+        "proxies.clash.yaml",
+        "proxies.xray.json",
+        "proxy.pac",
+    } {
+        got, err := os.ReadFile(filepath.Join(pf.outDir, name))
+        if err != nil {
+            t.Fatal(err)
+        }
+        golden := filepath.Join("testdata", "golden", name)
+        if *update {
+            if err := os.WriteFile(golden, got, 0644); err != nil {
+                t.Fatal(err)
+            }
+            continue
+        }
+        want, err := os.ReadFile(golden)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !bytes.Equal(got, want) {
+            t.Errorf("%s differs from %s:\n%s", name, golden, got)
+        }
+    }
+}
+
+// TestRetestKeepsProtocol reloads the proxies.txt written by a fixture run,
+// as -retest does, and checks every proxy keeps its protocol
+func TestRetestKeepsProtocol(t *testing.T) {
+    pf := NewProxyFetcher()
+    if err := pf.loadProxyFile(filepath.Join("testdata", "golden", "proxies.txt")); err != nil {
+        t.Fatal(err)
+    }
+
+    want := map[proxyKey]bool{
+        {"http", "192.0.2.10:8000"}:     true,
+        {"socks5", "192.0.2.44:1080"}:   true,
+        {"http", "198.51.100.2:3128"}:   true,
+        {"socks4", "198.51.100.9:4145"}: true,
+        {"http", "203.0.113.7:8080"}:    true,
+    }
+    got := make(map[proxyKey]bool)
+    pf.proxies.Range(func(key, _ any) bool {
+        got[key.(proxyKey)] = true
+        return true
+    })
+    if len(got) != len(want) {
+        t.Errorf("loaded %d proxies, want %d", len(got), len(want))
+    }
+    for key := range want {
+        if !got[key] {
+            t.Errorf("%s not loaded", key)
+        }
+    }
+}
+
+// TestCancelledChecksKeepProxies checks that proxies whose checks were
+// aborted by a cancelled context are neither recorded nor dropped
+func TestCancelledChecksKeepProxies(t *testing.T) {
+    pf := NewProxyFetcher()
+    if err := pf.useFixtures("testdata/fixtures"); err != nil {
+        t.Fatal(err)
+    }
+    pf.history = &History{Proxies: make(map[string]*ProxyHistory)}
+    if err := pf.fetchAllProxies(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    stored := len(pf.candidates())
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if proxies := pf.checkAndFilterProxies(ctx, context.Background()); len(proxies) != 0 {
+        t.Errorf("got %d working proxies from cancelled checks", len(proxies))
+    }
+    if got := len(pf.candidates()); got != stored {
+        t.Errorf("%d proxies stored after cancelled checks, want %d", got, stored)
+    }
+    if len(pf.history.Proxies) != 0 {
+        t.Errorf("cancelled checks recorded in history: %v", pf.history.Proxies)
+    }
+}
+
+// TestRunPrunesHistory checks that every cycle drops the proxies not seen
+// alive within maxAge before saving the state
+func TestRunPrunesHistory(t *testing.T) {
+    t.Setenv("DISCORD_WEBHOOK", "")
+    t.Setenv("SLACK_WEBHOOK_URL", "")
+
+    pf := NewProxyFetcher()
+    if err := pf.useFixtures("testdata/fixtures"); err != nil {
+        t.Fatal(err)
+    }
+    pf.outDir = t.TempDir()
+    pf.history = &History{
+        path: filepath.Join(pf.outDir, "state.json"),
+        Proxies: map[string]*ProxyHistory{
+            "http://192.0.2.99:8080": {Dead: 1, LastAlive: fixtureTime.AddDate(0, 0, -2)},
+        },
+    }
+    pf.maxAge = 24 * time.Hour
+
+    if _, err := pf.run(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    saved, err := loadHistory(pf.history.path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, ok := saved.Proxies["http://192.0.2.99:8080"]; ok {
+        t.Error("stale proxy kept in the saved state")
+    }
+    if _, ok := saved.Proxies["socks5://192.0.2.44:1080"]; !ok {
+        t.Error("checked proxy missing from the saved state")
+    }
+}
+
+// TestWhitelistKeepsUncheckedProxies rewrites a whitelist checked with
+// -limit and expects only the checked proxies found dead to be dropped
+func TestWhitelistKeepsUncheckedProxies(t *testing.T) {
+    t.Setenv("DISCORD_WEBHOOK", "")
+    t.Setenv("SLACK_WEBHOOK_URL", "")
+
+    pf := NewProxyFetcher()
+    if err := pf.useFixtures("testdata/fixtures"); err != nil {
+        t.Fatal(err)
+    }
+    pf.outDir = t.TempDir()
+    pf.whitelistFile = filepath.Join(pf.outDir, "whitelist.txt")
+    pf.retestFile = pf.whitelistFile
+    pf.limit = 2
+    // 192.0.2.1 works and every other proxy is dead
+    pf.transportFunc = func(proxy string, info ProxyInfo) (http.RoundTripper, error) {
+        if proxy == "192.0.2.1:8080" {
+            return mockCheckTransport(proxy, info)
+        }
+        return roundTripFunc(func(*http.Request) (*http.Response, error) {
+            return nil, errors.New("connection refused")
+        }), nil
+    }
+    whitelist := "http://192.0.2.1:8080\nhttp://192.0.2.2:8080\nsocks5://192.0.2.3:1080\nhttp://192.0.2.4:8080\n"
+    if err := os.WriteFile(pf.whitelistFile, []byte(whitelist), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := pf.run(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    if len(pf.dead) != 1 {
+        t.Fatalf("%d proxies found dead, want 1 of the 2 checked", len(pf.dead))
+    }
+    got, err := os.ReadFile(pf.whitelistFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var want []string
+    for _, line := range strings.Split(strings.TrimSpace(whitelist), "\n") {
+        proxy, _ := parseProxyLine(line, "http")
+        if !pf.dead[proxyKey{proxy.Protocol, proxy.Proxy}] {
+            want = append(want, line)
+        }
+    }
+    if strings.TrimSpace(string(got)) != strings.Join(want, "\n") {
+        t.Errorf("whitelist = %q, want %q", got, want)
+    }
+}
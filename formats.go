@@ -0,0 +1,147 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "net"
+    "strconv"
+)
+
+// outputFormats are the outputs saveProxies can write, as named in -format
+var outputFormats = []string{"proxychains", "txt", "json", "csv", "clash", "xray", "pac"}
+
+// defaultFormats are written when -format is not given
+var defaultFormats = []string{"proxychains", "txt", "json"}
+
+func knownFormat(name string) bool {
+    for _, format := range outputFormats {
+        if format == name {
+            return true
+        }
+    }
+    return false
+}
+
+// clashType returns the Clash proxy type for a protocol. Clash has no SOCKS4
+// support, so those proxies are left out.
+func clashType(protocol string) (string, bool) {
+    switch protocol {
+    case "http", "https":
+        return "http", true
+    case "socks5":
+        return "socks5", true
+    default:
+        return "", false
+    }
+}
+
+// writeProxiesClash writes the proxies as a Clash/Mihomo "proxies" block
+// that can be pasted into or included from a Clash config
+func writeProxiesClash(path string, proxies []ProxyResult) error {
+    var buf bytes.Buffer
+    buf.WriteString("proxies:\n")
+    for _, proxy := range proxies {
+        proxyType, ok := clashType(proxy.Protocol)
+        if !ok {
+            continue
+        }
+        host, port, err := net.SplitHostPort(proxy.Proxy)
+        if err != nil {
+            continue
+        }
+        fmt.Fprintf(&buf, "  - name: %s\n", strconv.Quote(proxy.Protocol+"-"+proxy.Proxy))
+        fmt.Fprintf(&buf, "    type: %s\n", proxyType)
+        fmt.Fprintf(&buf, "    server: %s\n", strconv.Quote(host))
+        fmt.Fprintf(&buf, "    port: %s\n", port)
+        if proxy.Username != "" {
+            fmt.Fprintf(&buf, "    username: %s\n", strconv.Quote(proxy.Username))
+            fmt.Fprintf(&buf, "    password: %s\n", strconv.Quote(proxy.Password))
+        }
+    }
+    return writeFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// xrayOutbound is an Xray/v2ray outbound for an http or socks proxy
+type xrayOutbound struct {
+    Tag      string `json:"tag"`
+    Protocol string `json:"protocol"`
+    Settings struct {
+        Servers []xrayServer `json:"servers"`
+    } `json:"settings"`
+}
+
+type xrayServer struct {
+    Address string     `json:"address"`
+    Port    int        `json:"port"`
+    Users   []xrayUser `json:"users,omitempty"`
+}
+
+type xrayUser struct {
+    User string `json:"user"`
+    Pass string `json:"pass"`
+}
+
+// xrayProtocol returns the Xray outbound protocol for a proxy protocol. Only
+// SOCKS5 is handled by the socks outbound of both Xray and v2ray, so SOCKS4
+// proxies are left out.
+func xrayProtocol(protocol string) (string, bool) {
+    switch protocol {
+    case "http", "https":
+        return "http", true
+    case "socks5":
+        return "socks", true
+    default:
+        return "", false
+    }
+}
+
+// writeProxiesXray writes the proxies as an Xray/v2ray "outbounds" array
+// tagged proxy-1, proxy-2 and so on in output order
+func writeProxiesXray(path string, proxies []ProxyResult) error {
+    outbounds := []xrayOutbound{}
+    for _, proxy := range proxies {
+        protocol, ok := xrayProtocol(proxy.Protocol)
+        if !ok {
+            continue
+        }
+        record := newProxyRecord(proxy)
+        if record.Port == 0 {
+            continue
+        }
+
+        server := xrayServer{Address: record.IP, Port: record.Port}
+        if proxy.Username != "" {
+            server.Users = []xrayUser{{User: proxy.Username, Pass: proxy.Password}}
+        }
+        outbound := xrayOutbound{
+            Tag:      fmt.Sprintf("proxy-%d", len(outbounds)+1),
+            Protocol: protocol,
+        }
+        outbound.Settings.Servers = []xrayServer{server}
+        outbounds = append(outbounds, outbound)
+    }
+
+    data, err := json.MarshalIndent(map[string]interface{}{"outbounds": outbounds}, "", "  ")
+    if err != nil {
+        return err
+    }
+    return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// writeProxiesPAC writes a proxy auto-config file whose FindProxyForURL
+// tries the HTTP proxies in output order and then falls back to DIRECT.
+// SOCKS proxies are left out since browsers handle SOCKS in PAC files
+// inconsistently, and so are credentials, which PAC cannot carry.
+func writeProxiesPAC(path string, proxies []ProxyResult) error {
+    var buf bytes.Buffer
+    buf.WriteString("function FindProxyForURL(url, host) {\n    return \"")
+    for _, proxy := range proxies {
+        if proxy.Protocol != "http" && proxy.Protocol != "https" {
+            continue
+        }
+        fmt.Fprintf(&buf, "PROXY %s; ", proxy.Proxy)
+    }
+    buf.WriteString("DIRECT\";\n}\n")
+    return writeFileAtomic(path, buf.Bytes(), 0644)
+}
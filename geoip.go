@@ -0,0 +1,66 @@
+package main
+
+import (
+    "net"
+    "strings"
+
+    "github.com/oschwald/geoip2-golang"
+)
+
+// unknownCountry is recorded for proxies whose IP has no GeoIP entry
+const unknownCountry = "unknown"
+
+// lookupCountries sets the ISO country code of each proxy using the MaxMind
+// GeoLite2 Country (or City) database at path
+func lookupCountries(path string, proxies []ProxyResult) error {
+    db, err := geoip2.Open(path)
+    if err != nil {
+        return err
+    }
+    defer db.Close()
+
+    for i := range proxies {
+        // Keep a country listed by the source when GeoIP has none
+        if country := countryOf(db, proxies[i].Proxy); country != unknownCountry || proxies[i].Country == "" {
+            proxies[i].Country = country
+        }
+    }
+    return nil
+}
+
+func countryOf(db *geoip2.Reader, proxy string) string {
+    host, _, err := net.SplitHostPort(proxy)
+    if err != nil {
+        return unknownCountry
+    }
+
+    ip := net.ParseIP(host)
+    if ip == nil {
+        return unknownCountry
+    }
+
+    record, err := db.Country(ip)
+    if err != nil || record.Country.IsoCode == "" {
+        return unknownCountry
+    }
+    return record.Country.IsoCode
+}
+
+// filterCountries keeps only the proxies located in one of the given
+// country codes, compared case-insensitively
+func filterCountries(proxies []ProxyResult, countries []string) []ProxyResult {
+    allowed := make(map[string]bool, len(countries))
+    for _, c := range countries {
+        allowed[strings.ToUpper(c)] = true
+    }
+
+    var filtered []ProxyResult
+    for _, proxy := range proxies {
+        if allowed[strings.ToUpper(proxy.Country)] {
+            filtered = append(filtered, proxy)
+        }
+    }
+
+    infof("Country filter kept %d of %d working proxies", len(filtered), len(proxies))
+    return filtered
+}
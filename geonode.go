@@ -0,0 +1,136 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "log"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// geonodeAPI is the geonode proxy-list endpoint built into the defaults
+const geonodeAPI = "https://proxylist.geonode.com/api/proxy-list"
+
+// GeonodeQuery holds the geonode API parameters that can be set from the
+// config file and flags. Empty fields keep their current value when merged.
+type GeonodeQuery struct {
+    SortBy    string   `json:"sort_by"`   // e.g. lastChecked, speed or uptime
+    SortType  string   `json:"sort_type"` // asc or desc
+    Protocols []string `json:"protocols"` // http, https, socks4, socks5
+    Anonymity string   `json:"anonymity"` // elite, anonymous or transparent; any when empty
+}
+
+var defaultGeonodeQuery = GeonodeQuery{
+    SortBy:    "lastChecked",
+    SortType:  "desc",
+    Protocols: []string{"http", "https"},
+}
+
+// merge returns q with the non-empty fields of other applied on top
+func (q GeonodeQuery) merge(other GeonodeQuery) GeonodeQuery {
+    if other.SortBy != "" {
+        q.SortBy = other.SortBy
+    }
+    if other.SortType != "" {
+        q.SortType = other.SortType
+    }
+    if len(other.Protocols) > 0 {
+        q.Protocols = other.Protocols
+    }
+    if other.Anonymity != "" {
+        q.Anonymity = other.Anonymity
+    }
+    return q
+}
+
+// geonodeSource returns the geonode API source for the query. Its protocol,
+// used when the API does not tag a proxy, is the first one requested.
+func geonodeSource(q GeonodeQuery) Source {
+    query := url.Values{
+        "limit":     {"500"},
+        "page":      {"1"},
+        "sort_by":   {q.SortBy},
+        "sort_type": {q.SortType},
+        "protocols": {strings.Join(q.Protocols, ",")},
+    }
+    if q.Anonymity != "" {
+        query.Set("anonymityLevel", q.Anonymity)
+    }
+    protocol := "http"
+    if len(q.Protocols) > 0 {
+        protocol = geonodeProtocol(q.Protocols[0])
+    }
+    return Source{
+        URL:      geonodeAPI + "?" + query.Encode(),
+        Protocol: protocol,
+        Parser:   "geonode-json",
+    }
+}
+
+// setGeonodeQuery rebuilds the built-in geonode source from the query. It
+// does nothing when the sources no longer include it, such as after a config
+// file replaced them.
+func (pf *ProxyFetcher) setGeonodeQuery(q GeonodeQuery) {
+    pf.geonodeQuery = q
+    for i, source := range pf.sources {
+        if strings.HasPrefix(source.URL, geonodeAPI+"?") {
+            pf.sources[i] = geonodeSource(q)
+        }
+    }
+}
+
+// fetchGeonodePages fetches the geonode source and follows its
+// pagination, using the total count reported by the first page, up to
+// geonodeMaxPages pages. It returns the content of every page fetched; an
+// error is only returned if the first page fails.
+func (pf *ProxyFetcher) fetchGeonodePages(ctx context.Context, source Source) ([]string, error) {
+    first, err := pf.fetchURL(ctx, source, source.URL)
+    if err != nil {
+        return nil, err
+    }
+    pages := []string{first}
+
+    var data GeonodeResponse
+    if err := json.Unmarshal([]byte(first), &data); err != nil || len(data.Data) == 0 {
+        // parseProxyList reports malformed content
+        return pages, nil
+    }
+
+    u, err := url.Parse(source.URL)
+    if err != nil {
+        return pages, nil
+    }
+    query := u.Query()
+    startPage, err := strconv.Atoi(query.Get("page"))
+    if err != nil || startPage < 1 {
+        startPage = 1
+    }
+
+    pageSize := len(data.Data)
+    totalPages := (data.Total + pageSize - 1) / pageSize
+    lastPage := totalPages
+    if max := startPage + pf.geonodeMaxPages - 1; lastPage > max {
+        lastPage = max
+    }
+
+    for page := startPage + 1; page <= lastPage; page++ {
+        select {
+        case <-time.After(pf.geonodePageDelay):
+        case <-ctx.Done():
+            return pages, nil
+        }
+
+        query.Set("page", strconv.Itoa(page))
+        u.RawQuery = query.Encode()
+        content, err := pf.fetchURL(ctx, source, u.String())
+        if err != nil {
+            log.Printf("Stopping geonode pagination at page %d: %v", page, err)
+            break
+        }
+        pages = append(pages, content)
+    }
+
+    return pages, nil
+}
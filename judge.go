@@ -0,0 +1,79 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "regexp"
+    "strings"
+)
+
+// judgeVarPattern matches the "NAME = value" lines printed by proxy judges
+// such as azenv.php, whether as plain text or wrapped in HTML
+var judgeVarPattern = regexp.MustCompile(`^([A-Z][A-Z0-9_]*)\s*=\s*(.*)$`)
+
+// htmlTagPattern strips markup from judge pages served as HTML
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// judgeAnonymity classifies the proxy with the first of judgeURLs that
+// answers, returning the last error if none does
+func (pf *ProxyFetcher) judgeAnonymity(ctx context.Context, proxy string, info ProxyInfo) (string, error) {
+    client, err := pf.proxyClient(proxy, info, pf.checkTimeout)
+    if err != nil {
+        return "", err
+    }
+    defer client.CloseIdleConnections()
+
+    for _, judgeURL := range pf.judgeURLs {
+        var headers map[string]string
+        headers, err = queryJudge(ctx, client, judgeURL)
+        if err == nil {
+            return classifyAnonymity(headers, pf.realIP), nil
+        }
+    }
+    return "", err
+}
+
+// queryJudge requests a proxy judge and returns the variables it echoed as
+// request headers: HTTP_X_FORWARDED_FOR becomes X-Forwarded-For, while
+// REMOTE_ADDR is kept under its own name so that classifyAnonymity also
+// compares the address the judge saw against the real IP
+func queryJudge(ctx context.Context, client *http.Client, judgeURL string) (map[string]string, error) {
+    req, err := http.NewRequestWithContext(ctx, "GET", judgeURL, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+    }
+
+    headers := make(map[string]string)
+    scanner := bufio.NewScanner(io.LimitReader(resp.Body, 1<<20))
+    for scanner.Scan() {
+        line := strings.TrimSpace(htmlTagPattern.ReplaceAllString(scanner.Text(), ""))
+        m := judgeVarPattern.FindStringSubmatch(line)
+        if m == nil {
+            continue
+        }
+        name, value := m[1], strings.TrimSpace(m[2])
+        if header, ok := strings.CutPrefix(name, "HTTP_"); ok {
+            name = http.CanonicalHeaderKey(strings.ReplaceAll(header, "_", "-"))
+        }
+        headers[name] = value
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    if _, ok := headers["REMOTE_ADDR"]; !ok {
+        return nil, fmt.Errorf("response does not look like a proxy judge: no REMOTE_ADDR")
+    }
+    return headers, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "time"
+)
+
+// errLocked is returned by lockFile when another process holds the lock
+var errLocked = errors.New("locked by another process")
+
+// acquireLock takes the lock file at path for this process, writing its
+// PID into it. When wait is set it retries until the lock is free or ctx is
+// done; otherwise it fails with errLocked right away.
+func acquireLock(ctx context.Context, path string, wait bool) (*os.File, error) {
+    for {
+        file, err := lockFile(path)
+        if err == nil {
+            file.Truncate(0)
+            fmt.Fprintf(file, "%d\n", os.Getpid())
+            return file, nil
+        }
+        if !errors.Is(err, errLocked) || !wait {
+            return nil, err
+        }
+        select {
+        case <-time.After(time.Second):
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        }
+    }
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+    "errors"
+    "os"
+)
+
+// lockFile is not supported without flock
+func lockFile(path string) (*os.File, error) {
+    return nil, errors.New("lock files are not supported on this platform")
+}
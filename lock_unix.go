@@ -0,0 +1,27 @@
+//go:build unix
+
+package main
+
+import (
+    "errors"
+    "os"
+    "syscall"
+)
+
+// lockFile takes an exclusive flock on path, creating the file if needed.
+// The lock is released when the file is closed or the process exits, so a
+// crashed run never leaves a stale lock behind.
+func lockFile(path string) (*os.File, error) {
+    file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+    if err != nil {
+        return nil, err
+    }
+    if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+        file.Close()
+        if errors.Is(err, syscall.EWOULDBLOCK) {
+            return nil, errLocked
+        }
+        return nil, err
+    }
+    return file, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+    "fmt"
+    "log"
+    "log/slog"
+    "os"
+)
+
+// logLevel is the verbosity chosen with -quiet and -verbose: warnings and
+// errors only, summaries as well (the default), or every proxy check too
+var logLevel = new(slog.LevelVar)
+
+// setupLogging configures the default logger. "text" keeps the standard
+// log output; "json" switches to structured JSON lines on stderr, which also
+// captures everything logged through the log package.
+func setupLogging(format string, level slog.Level) error {
+    logLevel.Set(level)
+    // The log package is bridged to slog at the info level, errors
+    // included, so quiet output is left to infof rather than the handler
+    handlerLevel := min(level, slog.LevelInfo)
+    switch format {
+    case "text":
+        slog.SetLogLoggerLevel(handlerLevel)
+        return nil
+    case "json":
+        slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: handlerLevel})))
+        return nil
+    default:
+        return fmt.Errorf("unknown log format %q (want text or json)", format)
+    }
+}
+
+// infof logs an informational message such as a summary, which -quiet
+// suppresses
+func infof(format string, args ...interface{}) {
+    if logLevel.Level() <= slog.LevelInfo {
+        log.Printf(format, args...)
+    }
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+)
+
+func TestEscapeMarkdownV2Code(t *testing.T) {
+    tests := []struct {
+        name string
+        text string
+        want string
+    }{
+        {
+            name: "header",
+            text: "# Proxychains Proxy List - Updated: 2024-01-01 00:00:00\n# Total working proxies: 2\n",
+            want: "# Proxychains Proxy List - Updated: 2024-01-01 00:00:00\n# Total working proxies: 2\n",
+        },
+        {name: "line", text: "socks5 192.0.2.1 1080", want: "socks5 192.0.2.1 1080"},
+        {name: "dot dash underscore", text: "my_proxy-01.example.com", want: "my_proxy-01.example.com"},
+        {name: "backtick", text: "a`b", want: "a\\`b"},
+        {name: "closing fence", text: "```", want: "\\`\\`\\`"},
+        {name: "backslash", text: `a\b`, want: `a\\b`},
+        {name: "escaped backtick", text: "\\`", want: "\\\\\\`"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := escapeMarkdownV2Code(tt.text); got != tt.want {
+                t.Errorf("escapeMarkdownV2Code(%q) = %q, want %q", tt.text, got, tt.want)
+            }
+        })
+    }
+}
+
+// TestCheckCollectsEveryResult checks thousands of mocked proxies through a
+// handful of workers and expects every result to be collected
+func TestCheckCollectsEveryResult(t *testing.T) {
+    const total = 5000
+    pf := NewProxyFetcher()
+    pf.maxWorkers = 4
+    pf.progressEvery = 0
+    pf.now = func() time.Time { return fixtureTime }
+    // Proxies on even ports work and the others fail
+    pf.transportFunc = func(proxy string, info ProxyInfo) (http.RoundTripper, error) {
+        _, port, _ := net.SplitHostPort(proxy)
+        if n, _ := strconv.Atoi(port); n%2 == 0 {
+            return mockCheckTransport(proxy, info)
+        }
+        return roundTripFunc(func(*http.Request) (*http.Response, error) {
+            return nil, errors.New("connection refused")
+        }), nil
+    }
+    for i := 0; i < total; i++ {
+        pf.storeProxy(fmt.Sprintf("10.0.%d.%d:%d", i/256, i%256, 1024+i), ProxyInfo{Protocol: "http", Source: "test"})
+    }
+
+    ctx := context.Background()
+    proxies := pf.checkAndFilterProxies(ctx, ctx)
+    if len(proxies) != total/2 {
+        t.Fatalf("got %d working proxies, want %d", len(proxies), total/2)
+    }
+    seen := make(map[string]bool, len(proxies))
+    for _, proxy := range proxies {
+        if seen[proxy.Proxy] {
+            t.Errorf("%s collected twice", proxy.Proxy)
+        }
+        seen[proxy.Proxy] = true
+    }
+}
+
+// TestStoreProxyAddsListedHTTPS stores a proxy from a plain http list and
+// then from an https one, and expects the https tag to be kept
+func TestStoreProxyAddsListedHTTPS(t *testing.T) {
+    pf := NewProxyFetcher()
+    pf.now = func() time.Time { return fixtureTime }
+    key := proxyKey{"http", "192.0.2.1:8080"}
+
+    if !pf.storeProxy(key.Address, ProxyInfo{Protocol: "http", Source: "plain"}) {
+        t.Fatal("first sighting not stored")
+    }
+    if pf.storeProxy(key.Address, ProxyInfo{Protocol: "http", Source: "https", ListedHTTPS: true}) {
+        t.Error("second sighting stored again")
+    }
+    pf.storeProxy(key.Address, ProxyInfo{Protocol: "http", Source: "plain"})
+
+    value, _ := pf.proxies.Load(key)
+    info := value.(ProxyInfo)
+    if !info.ListedHTTPS {
+        t.Error("https tag of the second sighting lost")
+    }
+    if info.Source != "plain" {
+        t.Errorf("source = %q, want the first sighting's", info.Source)
+    }
+}
+
+// TestCheckProxyAttempts checks that -check-retries counts the attempts in
+// total and that checking stops at the first success
+func TestCheckProxyAttempts(t *testing.T) {
+    tests := []struct {
+        name      string
+        attempts  int
+        failures  int // failed attempts before the proxy answers
+        wantValid bool
+        wantCalls int
+    }{
+        {name: "default", attempts: 1, failures: 0, wantValid: true, wantCalls: 1},
+        {name: "stops at first success", attempts: 3, failures: 1, wantValid: true, wantCalls: 2},
+        {name: "last attempt", attempts: 3, failures: 2, wantValid: true, wantCalls: 3},
+        {name: "dead", attempts: 2, failures: 5, wantValid: false, wantCalls: 2},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            pf := NewProxyFetcher()
+            pf.testURLs = []string{"http://example.com/"}
+            pf.checkAttempts = tt.attempts
+            pf.now = func() time.Time { return fixtureTime }
+            calls := 0
+            pf.transportFunc = func(proxy string, info ProxyInfo) (http.RoundTripper, error) {
+                return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+                    calls++
+                    if calls <= tt.failures {
+                        return nil, errors.New("timeout")
+                    }
+                    transport, _ := mockCheckTransport(proxy, info)
+                    return transport.RoundTrip(req)
+                }), nil
+            }
+
+            valid, _ := pf.checkProxy(context.Background(), "192.0.2.1:8080", ProxyInfo{Protocol: "http"})
+            if valid != tt.wantValid {
+                t.Errorf("valid = %v, want %v", valid, tt.wantValid)
+            }
+            if calls != tt.wantCalls {
+                t.Errorf("ran %d attempts, want %d", calls, tt.wantCalls)
+            }
+        })
+    }
+}
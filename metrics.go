@@ -0,0 +1,56 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+    proxiesFetched = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "proxies_fetched_total",
+        Help: "Unique proxies parsed from all sources.",
+    })
+    proxiesChecked = promauto.NewCounterVec(prometheus.CounterOpts{
+        Name: "proxies_checked_total",
+        Help: "Proxies checked, by result (valid or invalid).",
+    }, []string{"result"})
+    proxiesValid = promauto.NewGauge(prometheus.GaugeOpts{
+        Name: "proxies_valid",
+        Help: "Working proxies found by the last check.",
+    })
+    checkDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+        Name:    "check_duration_seconds",
+        Help:    "Time taken to check a single proxy.",
+        Buckets: prometheus.ExponentialBuckets(0.1, 2, 8),
+    })
+    sourceFetches = promauto.NewCounterVec(prometheus.CounterOpts{
+        Name: "source_fetches_total",
+        Help: "Source fetches, by source URL and result (success or failure).",
+    }, []string{"source", "result"})
+)
+
+// serveMetrics exposes /metrics on addr until ctx is done
+func serveMetrics(ctx context.Context, addr string) {
+    mux := http.NewServeMux()
+    mux.Handle("/metrics", promhttp.Handler())
+    server := &http.Server{Addr: addr, Handler: mux}
+
+    go func() {
+        <-ctx.Done()
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        server.Shutdown(shutdownCtx)
+    }()
+
+    infof("Serving metrics on %s/metrics", addr)
+    if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+        log.Printf("Metrics server error: %v", err)
+    }
+}
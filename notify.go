@@ -0,0 +1,195 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "os"
+    "sort"
+    "strings"
+    "time"
+    "unicode/utf8"
+)
+
+// proxychainsMessage returns the header and the proxychains-formatted lines
+// shared by all notifiers. Credentials are left out since notification
+// channels are often public.
+func (pf *ProxyFetcher) proxychainsMessage(proxies []ProxyResult) (string, []string) {
+    timestamp := time.Now().Format("2006-01-02 15:04:05")
+    header := fmt.Sprintf("# Proxychains Proxy List - Updated: %s\n# Total working proxies: %d\n# Sources used: %d\n\n", timestamp, len(proxies), len(pf.sources))
+
+    var lines []string
+    for _, proxy := range proxies {
+        if line, ok := proxychainsLine(proxy, false); ok {
+            lines = append(lines, line)
+        }
+    }
+    return header, lines
+}
+
+// chunkCodeBlocks wraps the header and lines in Markdown code blocks for
+// monospace, splitting them into as many messages as needed so that every
+// message, including its opening fence, header and closing fence, is at most
+// maxSize bytes. Every message repeats the header; if the header alone would
+// not fit it is dropped, and lines longer than a whole message are truncated.
+func chunkCodeBlocks(header string, lines []string, maxSize int) []string {
+    const suffix = "```"
+    prefix := "```\n" + header
+    if len(prefix)+len(suffix) >= maxSize {
+        prefix = "```\n"
+    }
+    budget := maxSize - len(prefix) - len(suffix)
+
+    var messages []string
+    var body strings.Builder
+    for _, line := range lines {
+        next := line + "\n"
+        if len(next) > budget {
+            next = truncateEscaped(line, budget-1) + "\n"
+        }
+        if body.Len()+len(next) > budget {
+            messages = append(messages, prefix+body.String()+suffix)
+            body.Reset()
+        }
+        body.WriteString(next)
+    }
+    if body.Len() > 0 || len(messages) == 0 {
+        messages = append(messages, prefix+body.String()+suffix)
+    }
+    return messages
+}
+
+// truncateEscaped shortens s to at most n bytes without splitting a UTF-8
+// rune or a backslash escape such as "\`", which would otherwise leave a
+// dangling backslash escaping the newline after it
+func truncateEscaped(s string, n int) string {
+    if len(s) <= n {
+        return s
+    }
+    for n > 0 && !utf8.RuneStart(s[n]) {
+        n--
+    }
+    s = s[:n]
+    trailing := len(s) - len(strings.TrimRight(s, "\\"))
+    if trailing%2 == 1 {
+        s = s[:len(s)-1]
+    }
+    return s
+}
+
+// sendToDiscord posts the proxy list to the Discord webhook in DISCORD_WEBHOOK
+// in proxychains format
+func (pf *ProxyFetcher) sendToDiscord(proxies []ProxyResult) error {
+    webhookURL := os.Getenv("DISCORD_WEBHOOK")
+    if webhookURL == "" {
+        return fmt.Errorf("DISCORD_WEBHOOK not set")
+    }
+
+    if len(proxies) == 0 {
+        return fmt.Errorf("no proxies to send")
+    }
+
+    // Discord message content is limited to 2000 characters
+    header, proxyLines := pf.proxychainsMessage(proxies)
+    messages := chunkCodeBlocks(header, proxyLines, 2000)
+
+    for i, msg := range messages {
+        if err := sendDiscordMessage(webhookURL, msg); err != nil {
+            log.Printf("Failed to send message %d to Discord: %v", i+1, err)
+            return err
+        }
+    }
+
+    infof("Sent %d proxies to Discord", len(proxies))
+    return nil
+}
+
+// sendDiscordMessage posts a single message to a Discord webhook
+func sendDiscordMessage(webhookURL, message string) error {
+    payload, err := json.Marshal(map[string]string{"content": message})
+    if err != nil {
+        return err
+    }
+
+    resp, err := http.Post(webhookURL, "application/json", bytes.NewReader(payload))
+    if err != nil {
+        return fmt.Errorf("failed to send Discord message: %v", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        body, _ := io.ReadAll(resp.Body)
+        return fmt.Errorf("Discord API error: status %d, response: %s", resp.StatusCode, string(body))
+    }
+
+    return nil
+}
+
+// protocolCounts returns "protocol: count" pairs for the proxies, sorted by
+// protocol name
+func protocolCounts(proxies []ProxyResult) []string {
+    counts := make(map[string]int)
+    for _, proxy := range proxies {
+        counts[proxy.Protocol]++
+    }
+
+    var pairs []string
+    for protocol, count := range counts {
+        pairs = append(pairs, fmt.Sprintf("%s: %d", protocol, count))
+    }
+    sort.Strings(pairs)
+    return pairs
+}
+
+// sendToSlack posts a per-protocol summary followed by the proxy list in
+// proxychains format to the Slack incoming webhook in SLACK_WEBHOOK_URL
+func (pf *ProxyFetcher) sendToSlack(proxies []ProxyResult) error {
+    webhookURL := os.Getenv("SLACK_WEBHOOK_URL")
+    if webhookURL == "" {
+        return fmt.Errorf("SLACK_WEBHOOK_URL not set")
+    }
+
+    if len(proxies) == 0 {
+        return fmt.Errorf("no proxies to send")
+    }
+
+    summary := fmt.Sprintf("*%d working proxies* (%s)", len(proxies), strings.Join(protocolCounts(proxies), ", "))
+
+    // Slack truncates long messages, so keep each code block to 3000 characters
+    header, proxyLines := pf.proxychainsMessage(proxies)
+    messages := append([]string{summary}, chunkCodeBlocks(header, proxyLines, 3000)...)
+
+    for i, msg := range messages {
+        if err := sendSlackMessage(webhookURL, msg); err != nil {
+            log.Printf("Failed to send message %d to Slack: %v", i+1, err)
+            return err
+        }
+    }
+
+    infof("Sent %d proxies to Slack", len(proxies))
+    return nil
+}
+
+// sendSlackMessage posts a single message to a Slack incoming webhook
+func sendSlackMessage(webhookURL, message string) error {
+    payload, err := json.Marshal(map[string]string{"text": message})
+    if err != nil {
+        return err
+    }
+
+    resp, err := http.Post(webhookURL, "application/json", bytes.NewReader(payload))
+    if err != nil {
+        return fmt.Errorf("failed to send Slack message: %v", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        body, _ := io.ReadAll(resp.Body)
+        return fmt.Errorf("Slack API error: status %d, response: %s", resp.StatusCode, string(body))
+    }
+
+    return nil
+}
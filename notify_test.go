@@ -0,0 +1,68 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+    "unicode/utf8"
+)
+
+// TestChunkCodeBlocksLargeList splits thousands of lines, as sent to
+// Telegram, and checks every message fits and every line is sent once
+func TestChunkCodeBlocksLargeList(t *testing.T) {
+    header := escapeMarkdownV2Code("# Proxychains Proxy List - Updated: 2024-01-01 00:00:00\n# Total working proxies: 5000\n# Sources used: 3\n\n")
+    var lines []string
+    for i := 0; i < 5000; i++ {
+        lines = append(lines, escapeMarkdownV2Code(fmt.Sprintf("socks5 10.%d.%d.%d %d", i/65536, i/256%256, i%256, 1024+i)))
+    }
+
+    messages := chunkCodeBlocks(header, lines, 4096)
+    if len(messages) < 2 {
+        t.Fatalf("got %d messages, want the list split", len(messages))
+    }
+    var sent []string
+    for i, msg := range messages {
+        if len(msg) > 4096 {
+            t.Errorf("message %d is %d bytes", i, len(msg))
+        }
+        body, ok := strings.CutPrefix(msg, "```\n"+header)
+        if !ok || !strings.HasSuffix(body, "```") {
+            t.Fatalf("message %d is not a code block with the header: %q", i, msg)
+        }
+        sent = append(sent, strings.Split(strings.TrimSuffix(body, "\n```"), "\n")...)
+    }
+    if strings.Join(sent, "\n") != strings.Join(lines, "\n") {
+        t.Errorf("sent %d lines, want the %d given in order", len(sent), len(lines))
+    }
+}
+
+// TestChunkCodeBlocksTruncation checks that lines longer than a message are
+// cut on rune and escape boundaries
+func TestChunkCodeBlocksTruncation(t *testing.T) {
+    tests := []struct {
+        name string
+        line string
+    }{
+        {name: "escaped backticks", line: strings.Repeat(escapeMarkdownV2Code("`"), 100)},
+        {name: "escaped backslashes", line: strings.Repeat(escapeMarkdownV2Code(`\`), 100)},
+        {name: "multi-byte runes", line: strings.Repeat("é", 100) + strings.Repeat("€", 100)},
+        {name: "mixed", line: "x" + strings.Repeat("€"+escapeMarkdownV2Code("`"), 100)},
+    }
+
+    for _, tt := range tests {
+        for size := 20; size < 40; size++ {
+            for _, msg := range chunkCodeBlocks("", []string{tt.line}, size) {
+                if len(msg) > size {
+                    t.Errorf("%s: message is %d bytes, want at most %d", tt.name, len(msg), size)
+                }
+                if !utf8.ValidString(msg) {
+                    t.Errorf("%s: message splits a rune: %q", tt.name, msg)
+                }
+                body := strings.TrimSuffix(strings.TrimPrefix(msg, "```\n"), "\n```")
+                if trailing := len(body) - len(strings.TrimRight(body, `\`)); trailing%2 == 1 {
+                    t.Errorf("%s: message splits an escape: %q", tt.name, msg)
+                }
+            }
+        }
+    }
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "net"
+    "os"
+    "path/filepath"
+    "strconv"
+    "time"
+)
+
+// proxyRecord is the JSON representation of a working proxy
+type proxyRecord struct {
+    IP        string    `json:"ip"`
+    Port      int       `json:"port"`
+    Protocol  string    `json:"protocol"`
+    HTTPS     bool      `json:"https"` // CONNECT support, checked or listed by the source
+    LatencyMs int64     `json:"latency_ms"`
+    Source    string    `json:"source"`
+    CheckedAt time.Time `json:"checked_at"`
+    Anonymity string    `json:"anonymity,omitempty"`
+    Leaking   bool      `json:"leaking,omitempty"`
+    Country   string    `json:"country,omitempty"`
+    ASN       uint      `json:"asn,omitempty"`
+    Org       string    `json:"org,omitempty"`
+    PTR       string    `json:"ptr,omitempty"`
+    // SuccessRate is only present when uptime history is tracked
+    SuccessRate float64 `json:"success_rate,omitempty"`
+    // Throughput is in bytes per second, only present when measured
+    Throughput float64 `json:"throughput_bps,omitempty"`
+    Score      float64 `json:"score"`
+}
+
+func newProxyRecord(proxy ProxyResult) proxyRecord {
+    host, portStr, _ := net.SplitHostPort(proxy.Proxy)
+    port, _ := strconv.Atoi(portStr)
+    return proxyRecord{
+        IP:          host,
+        Port:        port,
+        Protocol:    proxy.Protocol,
+        HTTPS:       proxy.HTTPS,
+        LatencyMs:   proxy.Latency.Milliseconds(),
+        Source:      proxy.Source,
+        CheckedAt:   proxy.CheckedAt,
+        Anonymity:   proxy.Anonymity,
+        Leaking:     proxy.Leaking,
+        Country:     proxy.Country,
+        ASN:         proxy.ASN,
+        Org:         proxy.Org,
+        PTR:         proxy.PTR,
+        SuccessRate: proxy.SuccessRate,
+        Throughput:  proxy.Throughput,
+        Score:       proxy.Score,
+    }
+}
+
+// writeProxychains writes the proxies to path as a proxychains.conf
+// ProxyList section, including credentials
+func (pf *ProxyFetcher) writeProxychains(path string, proxies []ProxyResult) error {
+    var buf bytes.Buffer
+    timestamp := pf.now().Format("2006-01-02 15:04:05")
+    fmt.Fprintf(&buf, "# Proxychains configuration - Updated: %s\n", timestamp)
+    fmt.Fprintf(&buf, "# Total working proxies: %d\n", len(proxies))
+    fmt.Fprintf(&buf, "# Sources used: %d\n", len(pf.sources))
+    fmt.Fprintf(&buf, "# Format: <type> <ip> <port> [user pass]\n\n")
+
+    for _, proxy := range proxies {
+        if line, ok := proxychainsLine(proxy, true); ok {
+            fmt.Fprintf(&buf, "%s\n", line)
+        }
+    }
+    return writeFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// writeProxiesTxt writes the proxies to path one per line as
+// protocol://host:port, so -retest keeps their protocol, each followed by a
+// comment with its latency and whatever else was checked. Credentials are
+// only written when txtCredentials is set.
+func (pf *ProxyFetcher) writeProxiesTxt(path string, proxies []ProxyResult) error {
+    var buf bytes.Buffer
+    timestamp := pf.now().Format("2006-01-02 15:04:05")
+    fmt.Fprintf(&buf, "# Proxy List - Updated: %s\n", timestamp)
+    fmt.Fprintf(&buf, "# Total working proxies: %d\n", len(proxies))
+    fmt.Fprintf(&buf, "# Sources used: %d\n\n", len(pf.sources))
+
+    for _, proxy := range proxies {
+        comment := proxy.Latency.Round(time.Millisecond).String()
+        if pf.httpsTestURL != "" {
+            if proxy.HTTPS {
+                comment += " https"
+            } else {
+                comment += " http-only"
+            }
+        } else if proxy.HTTPS {
+            comment += " https"
+        }
+        if proxy.Anonymity != "" {
+            comment += " " + proxy.Anonymity
+        }
+        if pf.history != nil {
+            comment += fmt.Sprintf(" uptime %.0f%%", proxy.SuccessRate*100)
+        }
+        line := proxy.Protocol + "://" + proxy.Proxy
+        if pf.txtCredentials {
+            line = proxy.String()
+        }
+        fmt.Fprintf(&buf, "%s # %s\n", line, comment)
+    }
+    return writeFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// writeProxiesJSON writes the proxies to path as an indented JSON array
+func writeProxiesJSON(path string, proxies []ProxyResult) error {
+    records := make([]proxyRecord, 0, len(proxies))
+    for _, proxy := range proxies {
+        records = append(records, newProxyRecord(proxy))
+    }
+
+    data, err := json.MarshalIndent(records, "", "  ")
+    if err != nil {
+        return err
+    }
+    return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// jsonlStream appends working proxies to a JSONL file as they are found, so
+// a killed run still leaves its results behind
+type jsonlStream struct {
+    file *os.File
+    enc  *json.Encoder
+}
+
+func openJSONLStream(path string) (*jsonlStream, error) {
+    file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+    if err != nil {
+        return nil, err
+    }
+    return &jsonlStream{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// write appends the proxy as a single JSON line
+func (s *jsonlStream) write(proxy ProxyResult) error {
+    return s.enc.Encode(newProxyRecord(proxy))
+}
+
+func (s *jsonlStream) Close() error {
+    return s.file.Close()
+}
+
+// writeProxiesCSV writes the proxies to path as CSV with a header row
+func writeProxiesCSV(path string, proxies []ProxyResult) error {
+    var buf bytes.Buffer
+    w := csv.NewWriter(&buf)
+    w.Write([]string{"ip", "port", "protocol", "latency_ms", "country", "https"})
+    for _, proxy := range proxies {
+        record := newProxyRecord(proxy)
+        w.Write([]string{
+            record.IP,
+            strconv.Itoa(record.Port),
+            record.Protocol,
+            strconv.FormatInt(record.LatencyMs, 10),
+            record.Country,
+            strconv.FormatBool(record.HTTPS),
+        })
+    }
+    w.Flush()
+    if err := w.Error(); err != nil {
+        return err
+    }
+    return writeFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// writeProxyList rewrites a proxy list file with the given proxies, one
+// protocol://[user:pass@]host:port per line so their protocols survive the
+// next load.
+func writeProxyList(path string, proxies []ProxyResult) error {
+    sorted := append([]ProxyResult(nil), proxies...)
+    sortByAddress(sorted)
+
+    var buf bytes.Buffer
+    for _, proxy := range sorted {
+        fmt.Fprintln(&buf, proxy.String())
+    }
+
+    return writeFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// dropProxies rewrites the proxy list file at path without the proxies in
+// dead, as writeProxyList does, and returns how many were kept
+func dropProxies(path string, dead map[proxyKey]bool) (int, error) {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return 0, err
+    }
+    listed, err := parsePlainHostPort(string(content), Source{URL: path, Protocol: "http"})
+    if err != nil {
+        return 0, err
+    }
+
+    var kept []ProxyResult
+    seen := make(map[proxyKey]bool, len(listed))
+    for _, proxy := range listed {
+        key := proxyKey{proxy.Protocol, proxy.Proxy}
+        if !dead[key] && !seen[key] {
+            seen[key] = true
+            kept = append(kept, proxy)
+        }
+    }
+    return len(kept), writeProxyList(path, kept)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+    tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+    if err != nil {
+        return err
+    }
+    // Removing fails harmlessly once the file has been renamed
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Chmod(perm); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), path)
+}
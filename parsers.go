@@ -0,0 +1,321 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "net"
+    "strconv"
+    "strings"
+)
+
+// ParserFunc reads the proxies listed in the content of a source
+type ParserFunc func(content string, source Source) ([]ProxyResult, error)
+
+// parsers maps the parser names usable in a source's config to their
+// implementation. "geonode" and "plain" are kept as aliases of the names
+// used before the registry existed.
+var parsers = map[string]ParserFunc{
+    "geonode-json":           parseGeonodeJSON,
+    "plain-host-port":        parsePlainHostPort,
+    "generic-json":           parseGenericJSON,
+    "ip-port-columns":        parseIPPortColumns,
+    "proxy-list-download-v2": parseProxyListDownloadV2,
+    "geonode":                parseGeonodeJSON,
+    "plain":                  parsePlainHostPort,
+}
+
+// parser returns the parser name for the source, detecting it from the URL
+// when none is configured
+func (s Source) parser() string {
+    switch {
+    case s.Parser == "geonode":
+        return "geonode-json"
+    case s.Parser == "plain":
+        return "plain-host-port"
+    case s.Parser != "":
+        return s.Parser
+    case strings.Contains(s.URL, "api") && strings.Contains(s.URL, "geonode"):
+        return "geonode-json"
+    case strings.Contains(s.URL, "proxy-list.download/api/v2"):
+        return "proxy-list-download-v2"
+    default:
+        return "plain-host-port"
+    }
+}
+
+// parseGeonodeJSON reads the geonode proxy-list API response
+func parseGeonodeJSON(content string, source Source) ([]ProxyResult, error) {
+    var data GeonodeResponse
+    if err := json.Unmarshal([]byte(content), &data); err != nil {
+        return nil, err
+    }
+
+    var proxies []ProxyResult
+    for _, item := range data.Data {
+        proxy, ok := normalizeProxy(item.IP, item.Port)
+        if !ok {
+            continue
+        }
+        protocols := item.Protocols
+        if len(protocols) == 0 {
+            protocols = []string{source.Protocol}
+        }
+        // Entries listing several protocols are stored once per protocol,
+        // with "http" and "https" both checked as http and tagged as https
+        // if either is
+        listedHTTPS := false
+        for _, protocol := range protocols {
+            if strings.EqualFold(protocol, "https") {
+                listedHTTPS = true
+            }
+        }
+        seen := make(map[string]bool, len(protocols))
+        for _, protocol := range protocols {
+            protocol = geonodeProtocol(protocol)
+            if seen[protocol] {
+                continue
+            }
+            seen[protocol] = true
+            proxies = append(proxies, ProxyResult{
+                Proxy: proxy,
+                ProxyInfo: ProxyInfo{
+                    Protocol:    protocol,
+                    Source:      source.URL,
+                    ListedHTTPS: listedHTTPS && protocol == "http",
+                },
+            })
+        }
+    }
+    return proxies, nil
+}
+
+// geonodeProtocol maps a protocol from the geonode API to the one used to
+// check the proxy. Geonode lists CONNECT-capable HTTP proxies as "https",
+// which are still reached over plain HTTP.
+func geonodeProtocol(protocol string) string {
+    protocol = strings.ToLower(protocol)
+    if protocol == "https" {
+        return "http"
+    }
+    return protocol
+}
+
+// proxyListDownloadV2 is the response of the proxy-list.download v2 API
+type proxyListDownloadV2 struct {
+    List []struct {
+        IP        string `json:"IP"`
+        Port      string `json:"PORT"`
+        Anonymity string `json:"ANON"`
+        Country   string `json:"ISO"`
+    } `json:"LISTA"`
+}
+
+// parseProxyListDownloadV2 reads the proxy-list.download v2 JSON API, which
+// lists the country and anonymity of every proxy. The protocol is the one
+// requested in the URL, taken from the source.
+func parseProxyListDownloadV2(content string, source Source) ([]ProxyResult, error) {
+    var data proxyListDownloadV2
+    if err := json.Unmarshal([]byte(content), &data); err != nil {
+        return nil, err
+    }
+
+    var proxies []ProxyResult
+    for _, item := range data.List {
+        proxy, ok := normalizeProxy(item.IP, item.Port)
+        if !ok {
+            continue
+        }
+        proxies = append(proxies, ProxyResult{
+            Proxy: proxy,
+            ProxyInfo: ProxyInfo{
+                Protocol:        source.Protocol,
+                Source:          source.URL,
+                ListedCountry:   strings.ToUpper(item.Country),
+                ListedAnonymity: listedAnonymity(item.Anonymity),
+            },
+        })
+    }
+    return proxies, nil
+}
+
+// listedAnonymity maps an anonymity level as published by a source to the
+// levels used by the anonymity check, or "" if it is not recognised
+func listedAnonymity(level string) string {
+    level = strings.ToLower(level)
+    switch {
+    case strings.Contains(level, "elite"), strings.Contains(level, "high"):
+        return AnonymityElite
+    case strings.Contains(level, "anonymous"):
+        return AnonymityAnonymous
+    case strings.Contains(level, "transparent"):
+        return AnonymityTransparent
+    default:
+        return ""
+    }
+}
+
+// parsePlainHostPort reads one proxy per line in the form
+// [scheme://][user:pass@]host:port, ignoring anything after the first
+// whitespace-separated field and lines starting with '#'
+func parsePlainHostPort(content string, source Source) ([]ProxyResult, error) {
+    var proxies []ProxyResult
+    scanner := bufio.NewScanner(strings.NewReader(content))
+    for scanner.Scan() {
+        if proxy, ok := parseProxyLine(scanner.Text(), source.Protocol); ok {
+            proxy.Source = source.URL
+            proxies = append(proxies, proxy)
+        }
+    }
+    return proxies, scanner.Err()
+}
+
+// parseIPPortColumns reads lines listing the host and port as separate
+// whitespace-separated columns, such as "1.2.3.4 8080", ignoring any further
+// columns
+func parseIPPortColumns(content string, source Source) ([]ProxyResult, error) {
+    var proxies []ProxyResult
+    scanner := bufio.NewScanner(strings.NewReader(content))
+    for scanner.Scan() {
+        fields := strings.Fields(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+        if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+            continue
+        }
+        if proxy, ok := normalizeProxy(fields[0], fields[1]); ok {
+            proxies = append(proxies, ProxyResult{
+                Proxy:     proxy,
+                ProxyInfo: ProxyInfo{Protocol: source.Protocol, Source: source.URL},
+            })
+        }
+    }
+    return proxies, scanner.Err()
+}
+
+// parseProxyLine reads a [protocol://][user:pass@]host:port line, ignoring
+// anything after the first field such as a trailing comment or extra
+// protocol, country and anonymity columns. Fields may be separated by any
+// run of spaces or tabs. protocol is used when the line has no scheme. A
+// leading byte order mark, as left by some editors on the first line, is
+// ignored. Blank lines, comments and malformed entries are rejected.
+func parseProxyLine(line, protocol string) (ProxyResult, bool) {
+    line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+    if line == "" || strings.HasPrefix(line, "#") || !strings.Contains(line, ":") {
+        return ProxyResult{}, false
+    }
+
+    proxy := strings.Fields(line)[0]
+    if i := strings.Index(proxy, "://"); i != -1 {
+        protocol = strings.ToLower(proxy[:i])
+        proxy = proxy[i+3:]
+    }
+    var username, password string
+    if at := strings.LastIndex(proxy, "@"); at != -1 {
+        username, password, _ = strings.Cut(proxy[:at], ":")
+        proxy = proxy[at+1:]
+    }
+    // SplitHostPort handles both host:port and bracketed [ipv6]:port
+    host, port, err := net.SplitHostPort(proxy)
+    if err != nil {
+        return ProxyResult{}, false
+    }
+
+    proxy, ok := normalizeProxy(host, port)
+    if !ok {
+        return ProxyResult{}, false
+    }
+    return ProxyResult{
+        Proxy: proxy,
+        ProxyInfo: ProxyInfo{
+            Protocol: protocol,
+            Username: username,
+            Password: password,
+        },
+    }, true
+}
+
+// JSONFields configures the generic-json parser. Each value is a
+// dot-separated path such as "data.proxies" or "address.port"; numeric path
+// elements index into arrays.
+type JSONFields struct {
+    List     string `json:"list"`     // path to the array of proxies, empty if the response is the array
+    IP       string `json:"ip"`       // path to the host within each entry
+    Port     string `json:"port"`     // path to the port within each entry
+    Protocol string `json:"protocol"` // optional path to the protocol within each entry
+}
+
+// parseGenericJSON reads a JSON API response using the field paths
+// configured on the source
+func parseGenericJSON(content string, source Source) ([]ProxyResult, error) {
+    if source.Fields == nil {
+        return nil, fmt.Errorf("generic-json parser requires fields")
+    }
+    fields := source.Fields
+
+    var doc interface{}
+    if err := json.Unmarshal([]byte(content), &doc); err != nil {
+        return nil, err
+    }
+
+    list, ok := jsonPath(doc, fields.List).([]interface{})
+    if !ok {
+        return nil, fmt.Errorf("%q is not an array", fields.List)
+    }
+
+    var proxies []ProxyResult
+    for _, entry := range list {
+        host := jsonString(jsonPath(entry, fields.IP))
+        port := jsonString(jsonPath(entry, fields.Port))
+        proxy, ok := normalizeProxy(host, port)
+        if !ok {
+            continue
+        }
+
+        protocol := source.Protocol
+        if fields.Protocol != "" {
+            if p := jsonString(jsonPath(entry, fields.Protocol)); p != "" {
+                protocol = strings.ToLower(p)
+            }
+        }
+
+        proxies = append(proxies, ProxyResult{
+            Proxy:     proxy,
+            ProxyInfo: ProxyInfo{Protocol: protocol, Source: source.URL},
+        })
+    }
+    return proxies, nil
+}
+
+// jsonPath follows a dot-separated path through decoded JSON, returning nil
+// if any element is missing
+func jsonPath(value interface{}, path string) interface{} {
+    if path == "" {
+        return value
+    }
+    for _, key := range strings.Split(path, ".") {
+        switch v := value.(type) {
+        case map[string]interface{}:
+            value = v[key]
+        case []interface{}:
+            i, err := strconv.Atoi(key)
+            if err != nil || i < 0 || i >= len(v) {
+                return nil
+            }
+            value = v[i]
+        default:
+            return nil
+        }
+    }
+    return value
+}
+
+// jsonString formats a decoded JSON scalar as a string
+func jsonString(value interface{}) string {
+    switch v := value.(type) {
+    case string:
+        return v
+    case float64:
+        return strconv.FormatFloat(v, 'f', -1, 64)
+    default:
+        return ""
+    }
+}
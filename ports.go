@@ -0,0 +1,60 @@
+package main
+
+import (
+    "fmt"
+    "net"
+    "strconv"
+    "strings"
+)
+
+// portRange is an inclusive range of ports
+type portRange struct {
+    from, to int
+}
+
+// portList is a set of allowed ports given as single ports and ranges
+type portList []portRange
+
+// parsePortList reads a comma-separated list like "80,443,1080-1090"
+func parsePortList(value string) (portList, error) {
+    var ports portList
+    for _, item := range strings.Split(value, ",") {
+        item = strings.TrimSpace(item)
+        if item == "" {
+            continue
+        }
+        fromStr, toStr, isRange := strings.Cut(item, "-")
+        if !isRange {
+            toStr = fromStr
+        }
+        from, err1 := strconv.Atoi(strings.TrimSpace(fromStr))
+        to, err2 := strconv.Atoi(strings.TrimSpace(toStr))
+        if err1 != nil || err2 != nil || from < 1 || to > 65535 || from > to {
+            return nil, fmt.Errorf("invalid port or range %q", item)
+        }
+        ports = append(ports, portRange{from, to})
+    }
+    return ports, nil
+}
+
+// allows reports whether the port of a host:port address is in the list. An
+// empty list allows every port.
+func (l portList) allows(address string) bool {
+    if len(l) == 0 {
+        return true
+    }
+    _, portStr, err := net.SplitHostPort(address)
+    if err != nil {
+        return false
+    }
+    port, err := strconv.Atoi(portStr)
+    if err != nil {
+        return false
+    }
+    for _, r := range l {
+        if port >= r.from && port <= r.to {
+            return true
+        }
+    }
+    return false
+}
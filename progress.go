@@ -0,0 +1,58 @@
+package main
+
+import (
+    "fmt"
+    "log/slog"
+    "os"
+    "time"
+)
+
+// checkProgress reports how far checkAndFilterProxies has got, redrawing a
+// single status line when stderr is a terminal and logging every n checks
+// otherwise. Nothing is reported with -quiet.
+type checkProgress struct {
+    total    int
+    every    int
+    checked  int
+    valid    int
+    start    time.Time
+    terminal bool
+}
+
+func newCheckProgress(total, every int) *checkProgress {
+    return &checkProgress{
+        total:    total,
+        every:    every,
+        start:    time.Now(),
+        terminal: isTerminal(os.Stderr),
+    }
+}
+
+// add records one finished check
+func (p *checkProgress) add(valid bool) {
+    p.checked++
+    if valid {
+        p.valid++
+    }
+
+    switch {
+    case logLevel.Level() > slog.LevelInfo:
+    case p.terminal:
+        fmt.Fprintf(os.Stderr, "\rChecked %d/%d proxies, %d working, %s elapsed", p.checked, p.total, p.valid, time.Since(p.start).Round(time.Second))
+    case p.every > 0 && p.checked%p.every == 0:
+        slog.Info("Check progress", "event", "check_progress", "checked", p.checked, "total", p.total, "valid", p.valid)
+    }
+}
+
+// finish ends the status line so later output starts on a fresh line
+func (p *checkProgress) finish() {
+    if p.terminal && p.checked > 0 && logLevel.Level() <= slog.LevelInfo {
+        fmt.Fprintln(os.Stderr)
+    }
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+    info, err := f.Stat()
+    return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+    "context"
+    "net"
+    "strings"
+    "sync"
+    "time"
+)
+
+// lookupPTRs sets the reverse DNS name of each proxy's IP, resolving at most
+// workers addresses at a time with timeout each. Proxies without a PTR
+// record are left with an empty name.
+func lookupPTRs(ctx context.Context, proxies []ProxyResult, timeout time.Duration, workers int) {
+    if workers < 1 {
+        workers = 1
+    }
+    sem := make(chan struct{}, workers)
+    var wg sync.WaitGroup
+    for i := range proxies {
+        host, _, err := net.SplitHostPort(proxies[i].Proxy)
+        if err != nil || net.ParseIP(host) == nil {
+            continue
+        }
+
+        wg.Add(1)
+        sem <- struct{}{}
+        go func(proxy *ProxyResult, ip string) {
+            defer wg.Done()
+            defer func() { <-sem }()
+
+            ctx, cancel := context.WithTimeout(ctx, timeout)
+            defer cancel()
+            names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+            if err != nil || len(names) == 0 {
+                return
+            }
+            proxy.PTR = strings.TrimSuffix(names[0], ".")
+        }(&proxies[i], host)
+    }
+    wg.Wait()
+}
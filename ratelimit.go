@@ -0,0 +1,52 @@
+package main
+
+import (
+    "context"
+    "net/url"
+    "sync"
+
+    "golang.org/x/time/rate"
+)
+
+// hostLimiter spaces out source requests made to the same host with one
+// token bucket per host
+type hostLimiter struct {
+    mu       sync.Mutex
+    limit    rate.Limit
+    burst    int
+    limiters map[string]*rate.Limiter
+}
+
+// newHostLimiter allows perSecond requests per host with the given burst.
+// A perSecond of 0 or less disables limiting.
+func newHostLimiter(perSecond float64, burst int) *hostLimiter {
+    if burst < 1 {
+        burst = 1
+    }
+    return &hostLimiter{
+        limit:    rate.Limit(perSecond),
+        burst:    burst,
+        limiters: make(map[string]*rate.Limiter),
+    }
+}
+
+// wait blocks until a request to the host of rawURL is allowed or ctx is done
+func (h *hostLimiter) wait(ctx context.Context, rawURL string) error {
+    if h == nil || h.limit <= 0 {
+        return nil
+    }
+    u, err := url.Parse(rawURL)
+    if err != nil {
+        return nil
+    }
+
+    h.mu.Lock()
+    limiter, ok := h.limiters[u.Hostname()]
+    if !ok {
+        limiter = rate.NewLimiter(h.limit, h.burst)
+        h.limiters[u.Hostname()] = limiter
+    }
+    h.mu.Unlock()
+
+    return limiter.Wait(ctx)
+}
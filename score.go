@@ -0,0 +1,102 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ScoreWeights sets how much each quality signal contributes to a proxy's
+// score
+type ScoreWeights struct {
+    Latency   float64
+    Uptime    float64
+    Anonymity float64
+}
+
+var defaultScoreWeights = ScoreWeights{Latency: 0.5, Uptime: 0.3, Anonymity: 0.2}
+
+// anonymityScores rates each anonymity level between 0 and 1
+var anonymityScores = map[string]float64{
+    AnonymityElite:       1,
+    AnonymityAnonymous:   0.6,
+    AnonymityTransparent: 0,
+}
+
+// scoreProxy rates a proxy from 0 to 100 as the weighted average of
+//
+//   - latency:   1 - latency/maxLatency, clamped to [0, 1]
+//   - uptime:    the success rate across runs
+//   - anonymity: 1 for elite, 0.6 for anonymous and 0 for transparent
+//
+// times 100. Signals that were not measured, uptime without a state file and
+// anonymity without -anonymity, are left out and the remaining weights are
+// renormalized, so a score only reflects what is known about the proxy.
+func scoreProxy(proxy ProxyResult, weights ScoreWeights, maxLatency time.Duration, withUptime bool) float64 {
+    var total, sum float64
+    if weights.Latency > 0 && maxLatency > 0 {
+        latency := 1 - float64(proxy.Latency)/float64(maxLatency)
+        latency = min(max(latency, 0), 1)
+        total += weights.Latency
+        sum += weights.Latency * latency
+    }
+    if weights.Uptime > 0 && withUptime {
+        total += weights.Uptime
+        sum += weights.Uptime * proxy.SuccessRate
+    }
+    if anonymity, ok := anonymityScores[proxy.Anonymity]; ok && weights.Anonymity > 0 {
+        total += weights.Anonymity
+        sum += weights.Anonymity * anonymity
+    }
+    if total == 0 {
+        return 0
+    }
+    return 100 * sum / total
+}
+
+// scoreProxies sets the score of every proxy
+func (pf *ProxyFetcher) scoreProxies(proxies []ProxyResult) {
+    for i := range proxies {
+        proxies[i].Score = scoreProxy(proxies[i], pf.scoreWeights, pf.maxLatency, pf.history != nil)
+    }
+}
+
+// sortByScore sorts proxies by descending score, breaking ties by address
+func sortByScore(proxies []ProxyResult) {
+    sortByAddress(proxies)
+    sort.SliceStable(proxies, func(i, j int) bool {
+        return proxies[i].Score > proxies[j].Score
+    })
+}
+
+// parseScoreWeights reads weights written as "latency=0.5,uptime=0.3,anonymity=0.2".
+// Signals left out keep their default weight.
+func parseScoreWeights(value string) (ScoreWeights, error) {
+    weights := defaultScoreWeights
+    for _, pair := range strings.Split(value, ",") {
+        if pair = strings.TrimSpace(pair); pair == "" {
+            continue
+        }
+        name, v, ok := strings.Cut(pair, "=")
+        if !ok {
+            return weights, fmt.Errorf("weight %q is not in name=value form", pair)
+        }
+        weight, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+        if err != nil || weight < 0 {
+            return weights, fmt.Errorf("invalid weight %q", pair)
+        }
+        switch strings.TrimSpace(name) {
+        case "latency":
+            weights.Latency = weight
+        case "uptime":
+            weights.Uptime = weight
+        case "anonymity":
+            weights.Anonymity = weight
+        default:
+            return weights, fmt.Errorf("unknown signal %q, expected latency, uptime or anonymity", name)
+        }
+    }
+    return weights, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "log"
+    "math/rand"
+    "net/http"
+    "sync"
+    "sync/atomic"
+    "time"
+)
+
+// proxyServer serves the latest working proxies over HTTP
+type proxyServer struct {
+    mu      sync.RWMutex
+    proxies []ProxyResult
+    next    atomic.Uint64 // round-robin position of /proxies/next
+    // health of the refresh cycles, reported by /healthz
+    refreshed    time.Time // end of the last cycle
+    refreshErr   error     // error of the last cycle, if any
+    lastSuccess  time.Time // end of the last cycle that found proxies
+    successCount int       // proxies found by that cycle
+}
+
+func newProxyServer() *proxyServer {
+    return &proxyServer{}
+}
+
+// update replaces the served proxies with the result of a check cycle. A
+// cycle counts as successful when it had no error and found proxies.
+func (s *proxyServer) update(proxies []ProxyResult, err error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.proxies = proxies
+    s.refreshed = time.Now()
+    s.refreshErr = err
+    if err == nil && len(proxies) > 0 {
+        s.lastSuccess = s.refreshed
+        s.successCount = len(proxies)
+    }
+}
+
+func (s *proxyServer) snapshot() []ProxyResult {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return s.proxies
+}
+
+// listen serves the API on addr until ctx is done
+func (s *proxyServer) listen(ctx context.Context, addr string) error {
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /proxies", s.handleProxies)
+    mux.HandleFunc("GET /proxies/random", s.handleRandom)
+    mux.HandleFunc("GET /proxies/next", s.handleNext)
+    mux.HandleFunc("GET /healthz", s.handleHealth)
+    server := &http.Server{Addr: addr, Handler: mux}
+
+    go func() {
+        <-ctx.Done()
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        server.Shutdown(shutdownCtx)
+    }()
+
+    infof("Serving working proxies on %s", addr)
+    if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+        return err
+    }
+    return nil
+}
+
+func (s *proxyServer) handleProxies(w http.ResponseWriter, r *http.Request) {
+    proxies := s.snapshot()
+    records := make([]proxyRecord, 0, len(proxies))
+    for _, proxy := range proxies {
+        records = append(records, newProxyRecord(proxy))
+    }
+    writeJSON(w, http.StatusOK, records)
+}
+
+func (s *proxyServer) handleRandom(w http.ResponseWriter, r *http.Request) {
+    proxies := s.snapshot()
+    if len(proxies) == 0 {
+        http.Error(w, "no working proxies", http.StatusServiceUnavailable)
+        return
+    }
+    writeJSON(w, http.StatusOK, newProxyRecord(proxies[rand.Intn(len(proxies))]))
+}
+
+// healthStatus is the /healthz response
+type healthStatus struct {
+    Status       string     `json:"status"` // ok or unhealthy
+    Error        string     `json:"error,omitempty"`
+    Refreshed    *time.Time `json:"refreshed,omitempty"`
+    LastSuccess  *time.Time `json:"last_success,omitempty"`
+    SuccessCount int        `json:"last_success_count"`
+}
+
+// handleHealth answers 200 when the last refresh succeeded and found
+// proxies, and 503 otherwise
+func (s *proxyServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    health := healthStatus{Status: "ok", SuccessCount: s.successCount}
+    if !s.refreshed.IsZero() {
+        health.Refreshed = &s.refreshed
+    }
+    if !s.lastSuccess.IsZero() {
+        health.LastSuccess = &s.lastSuccess
+    }
+
+    status := http.StatusOK
+    switch {
+    case s.refreshErr != nil:
+        health.Error = s.refreshErr.Error()
+    case len(s.proxies) == 0:
+        health.Error = "no working proxies"
+    }
+    if health.Error != "" {
+        health.Status = "unhealthy"
+        status = http.StatusServiceUnavailable
+    }
+    writeJSON(w, status, health)
+}
+
+// handleNext hands out the working proxies in turn, so clients can use the
+// endpoint as a simple round-robin balancer. The rotation carries on across
+// refreshes rather than restarting at the first proxy.
+func (s *proxyServer) handleNext(w http.ResponseWriter, r *http.Request) {
+    proxies := s.snapshot()
+    if len(proxies) == 0 {
+        http.Error(w, "no working proxies", http.StatusServiceUnavailable)
+        return
+    }
+    i := (s.next.Add(1) - 1) % uint64(len(proxies))
+    writeJSON(w, http.StatusOK, newProxyRecord(proxies[i]))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    if err := json.NewEncoder(w).Encode(v); err != nil {
+        log.Printf("Error writing response: %v", err)
+    }
+}
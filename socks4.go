@@ -0,0 +1,132 @@
+package main
+
+import (
+    "context"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "net"
+    "net/url"
+    "strconv"
+    "time"
+
+    netproxy "golang.org/x/net/proxy"
+)
+
+func init() {
+    netproxy.RegisterDialerType("socks4", newSOCKS4Dialer)
+    netproxy.RegisterDialerType("socks4a", newSOCKS4Dialer)
+}
+
+// socks4Dialer implements the SOCKS4/4a CONNECT handshake, which
+// golang.org/x/net/proxy does not provide out of the box
+type socks4Dialer struct {
+    proxyAddr string
+    userID    string
+    forward   netproxy.Dialer
+    // remoteDNS sends hostnames to the proxy through the SOCKS4a extension;
+    // plain SOCKS4 proxies get the address resolved locally instead
+    remoteDNS bool
+}
+
+func newSOCKS4Dialer(u *url.URL, forward netproxy.Dialer) (netproxy.Dialer, error) {
+    d := &socks4Dialer{proxyAddr: u.Host, forward: forward, remoteDNS: u.Scheme == "socks4a"}
+    if u.User != nil {
+        d.userID = u.User.Username()
+    }
+    return d, nil
+}
+
+func (d *socks4Dialer) Dial(network, addr string) (net.Conn, error) {
+    return d.DialContext(context.Background(), network, addr)
+}
+
+func (d *socks4Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+    if network != "tcp" && network != "tcp4" {
+        return nil, fmt.Errorf("socks4: unsupported network %s", network)
+    }
+
+    host, portStr, err := net.SplitHostPort(addr)
+    if err != nil {
+        return nil, err
+    }
+    port, err := strconv.Atoi(portStr)
+    if err != nil || port < 1 || port > 65535 {
+        return nil, fmt.Errorf("socks4: invalid port %s", portStr)
+    }
+    if !d.remoteDNS && net.ParseIP(host) == nil {
+        if host, err = resolveIPv4(ctx, host); err != nil {
+            return nil, err
+        }
+    }
+
+    var conn net.Conn
+    if cd, ok := d.forward.(netproxy.ContextDialer); ok {
+        conn, err = cd.DialContext(ctx, "tcp", d.proxyAddr)
+    } else {
+        conn, err = d.forward.Dial("tcp", d.proxyAddr)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+        defer conn.SetDeadline(time.Time{})
+    }
+
+    if err := d.handshake(conn, host, port); err != nil {
+        conn.Close()
+        return nil, err
+    }
+    return conn, nil
+}
+
+// resolveIPv4 returns the first IPv4 address of host, the only kind a plain
+// SOCKS4 request can carry
+func resolveIPv4(ctx context.Context, host string) (string, error) {
+    ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+    if err != nil {
+        return "", err
+    }
+    if len(ips) == 0 {
+        return "", fmt.Errorf("socks4: no IPv4 address for %s", host)
+    }
+    return ips[0].String(), nil
+}
+
+func (d *socks4Dialer) handshake(conn net.Conn, host string, port int) error {
+    req := []byte{4, 1, 0, 0}
+    binary.BigEndian.PutUint16(req[2:], uint16(port))
+
+    // Plain SOCKS4 only carries IPv4 addresses; with remoteDNS hostnames go
+    // through the SOCKS4a extension with a 0.0.0.x placeholder address
+    ip := net.ParseIP(host).To4()
+    if ip != nil {
+        req = append(req, ip...)
+    } else {
+        req = append(req, 0, 0, 0, 1)
+    }
+    req = append(req, d.userID...)
+    req = append(req, 0)
+    if ip == nil {
+        req = append(req, host...)
+        req = append(req, 0)
+    }
+
+    if _, err := conn.Write(req); err != nil {
+        return err
+    }
+
+    resp := make([]byte, 8)
+    if _, err := io.ReadFull(conn, resp); err != nil {
+        return err
+    }
+    if resp[0] != 0 {
+        return fmt.Errorf("socks4: invalid reply version %d", resp[0])
+    }
+    if resp[1] != 90 {
+        return fmt.Errorf("socks4: request rejected with code %d", resp[1])
+    }
+    return nil
+}
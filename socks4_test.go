@@ -0,0 +1,62 @@
+package main
+
+import (
+    "bytes"
+    "io"
+    "net"
+    "net/url"
+    "testing"
+
+    netproxy "golang.org/x/net/proxy"
+)
+
+// TestSOCKS4ResolvesLocally dials a hostname through a fake SOCKS4 server
+// and checks socks4 sends the resolved IPv4 address while socks4a sends the
+// hostname
+func TestSOCKS4ResolvesLocally(t *testing.T) {
+    tests := []struct {
+        scheme string
+        want   []byte // destination address and user ID of the request
+    }{
+        {scheme: "socks4", want: []byte{127, 0, 0, 1, 0}},
+        {scheme: "socks4a", want: append([]byte{0, 0, 0, 1, 0}, "localhost\x00"...)},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.scheme, func(t *testing.T) {
+            ln, err := net.Listen("tcp", "127.0.0.1:0")
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer ln.Close()
+
+            requests := make(chan []byte, 1)
+            go func() {
+                conn, err := ln.Accept()
+                if err != nil {
+                    return
+                }
+                defer conn.Close()
+                req := make([]byte, 4+len(tt.want))
+                io.ReadFull(conn, req)
+                requests <- req
+                conn.Write([]byte{0, 90, 0, 0, 0, 0, 0, 0})
+            }()
+
+            dialer, err := netproxy.FromURL(&url.URL{Scheme: tt.scheme, Host: ln.Addr().String()}, netproxy.Direct)
+            if err != nil {
+                t.Fatal(err)
+            }
+            conn, err := dialer.Dial("tcp", "localhost:80")
+            if err != nil {
+                t.Fatal(err)
+            }
+            conn.Close()
+
+            req := <-requests
+            if !bytes.Equal(req[:4], []byte{4, 1, 0, 80}) || !bytes.Equal(req[4:], tt.want) {
+                t.Errorf("request = %v, want destination %v", req, tt.want)
+            }
+        })
+    }
+}
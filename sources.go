@@ -0,0 +1,171 @@
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// loadSourcesFile reads a list of sources, one per line, each either a bare
+// URL or a protocol and URL separated by a pipe (socks5|https://...). Blank
+// lines and lines starting with '#' are skipped and the protocol defaults to
+// http. An "include path" line reads another sources file in its place,
+// relative to the including file.
+func loadSourcesFile(path string) ([]Source, error) {
+    return loadSourcesFileFrom(path, make(map[string]bool))
+}
+
+// loadSourcesFileFrom is loadSourcesFile tracking the files being read in
+// including, so include cycles are reported rather than followed forever
+func loadSourcesFileFrom(path string, including map[string]bool) ([]Source, error) {
+    abs, err := filepath.Abs(path)
+    if err != nil {
+        return nil, err
+    }
+    if including[abs] {
+        return nil, fmt.Errorf("%s includes itself", path)
+    }
+    including[abs] = true
+    defer delete(including, abs)
+
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    var sources []Source
+    scanner := bufio.NewScanner(file)
+    for n := 1; scanner.Scan(); n++ {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        if included, ok := strings.CutPrefix(line, "include "); ok {
+            included = strings.TrimSpace(included)
+            if !filepath.IsAbs(included) {
+                included = filepath.Join(filepath.Dir(path), included)
+            }
+            more, err := loadSourcesFileFrom(included, including)
+            if err != nil {
+                return nil, fmt.Errorf("line %d: %s: %v", n, included, err)
+            }
+            sources = append(sources, more...)
+            continue
+        }
+        source, err := parseSourceLine(line)
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %v", n, err)
+        }
+        sources = append(sources, source)
+    }
+    return sources, scanner.Err()
+}
+
+// parseSourcesList reads sources separated by commas or newlines, each in
+// the "[protocol|]url" form of a sources file, as found in $PROXY_SOURCES
+func parseSourcesList(list string) ([]Source, error) {
+    var sources []Source
+    for _, entry := range strings.FieldsFunc(list, func(r rune) bool {
+        return r == ',' || r == '\n'
+    }) {
+        if entry = strings.TrimSpace(entry); entry == "" {
+            continue
+        }
+        source, err := parseSourceLine(entry)
+        if err != nil {
+            return nil, err
+        }
+        sources = append(sources, source)
+    }
+    return sources, nil
+}
+
+// parseSourceLine reads a single "[protocol|]url" source entry
+func parseSourceLine(line string) (Source, error) {
+    source := Source{URL: line, Protocol: "http"}
+    if protocol, u, ok := strings.Cut(line, "|"); ok {
+        source.Protocol = strings.ToLower(strings.TrimSpace(protocol))
+        source.URL = strings.TrimSpace(u)
+    }
+
+    if !knownProtocol(source.Protocol) {
+        return Source{}, fmt.Errorf("unknown protocol %q", source.Protocol)
+    }
+    if !strings.HasPrefix(source.URL, "http://") && !strings.HasPrefix(source.URL, "https://") {
+        return Source{}, fmt.Errorf("invalid source URL %q", source.URL)
+    }
+    return source, nil
+}
+
+// mergeSources adds extra to the fetcher's sources, skipping URLs it already
+// has, or replaces them outright when mode is "replace"
+func (pf *ProxyFetcher) mergeSources(extra []Source, mode string) error {
+    switch mode {
+    case "replace":
+        pf.sources = nil
+    case "append":
+    default:
+        return fmt.Errorf("unknown sources mode %q, expected append or replace", mode)
+    }
+
+    seen := make(map[string]bool, len(pf.sources))
+    for _, source := range pf.sources {
+        seen[source.URL] = true
+    }
+    added := 0
+    for _, source := range extra {
+        if seen[source.URL] {
+            continue
+        }
+        seen[source.URL] = true
+        pf.sources = append(pf.sources, source)
+        added++
+    }
+    infof("Added %d sources (%s), %d in total", added, mode, len(pf.sources))
+    return nil
+}
+
+// wantsGeonode reports whether source is the built-in geonode source and
+// any of the protocols requested from it is wanted
+func (pf *ProxyFetcher) wantsGeonode(source Source) bool {
+    if !strings.HasPrefix(source.URL, geonodeAPI+"?") {
+        return false
+    }
+    for _, protocol := range pf.geonodeQuery.Protocols {
+        if pf.wantsProtocol(protocol) || pf.wantsProtocol(geonodeProtocol(protocol)) {
+            return true
+        }
+    }
+    return false
+}
+
+// knownProtocol reports whether protocol is one the checker can speak
+func knownProtocol(protocol string) bool {
+    switch protocol {
+    case "http", "https", "socks4", "socks5":
+        return true
+    }
+    return false
+}
+
+// wantsProtocol reports whether proxies of protocol should be fetched and
+// checked, which is always the case when no -protocols filter is set
+func (pf *ProxyFetcher) wantsProtocol(protocol string) bool {
+    return len(pf.protocols) == 0 || pf.protocols[protocol]
+}
+
+// filterSources drops the sources whose protocol is not wanted. The geonode
+// source lists several protocols and is kept if any of them is wanted.
+func (pf *ProxyFetcher) filterSources() {
+    var kept []Source
+    for _, source := range pf.sources {
+        if pf.wantsProtocol(source.Protocol) || pf.wantsGeonode(source) {
+            kept = append(kept, source)
+        }
+    }
+    infof("Protocol filter kept %d of %d sources", len(kept), len(pf.sources))
+    pf.sources = kept
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+    "strings"
+    "testing"
+)
+
+// TestFilterSourcesGeonodeProtocols checks that the geonode source is kept
+// when any protocol requested from it is wanted, not only the first
+func TestFilterSourcesGeonodeProtocols(t *testing.T) {
+    tests := []struct {
+        name      string
+        geonode   []string
+        protocols []string
+        want      bool
+    }{
+        {name: "first protocol", geonode: []string{"http", "socks5"}, protocols: []string{"http"}, want: true},
+        {name: "later protocol", geonode: []string{"http", "socks5"}, protocols: []string{"socks5"}, want: true},
+        {name: "https checked as http", geonode: []string{"https"}, protocols: []string{"http"}, want: true},
+        {name: "none wanted", geonode: []string{"http", "https"}, protocols: []string{"socks4"}, want: false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            pf := NewProxyFetcher()
+            pf.setGeonodeQuery(pf.geonodeQuery.merge(GeonodeQuery{Protocols: tt.geonode}))
+            pf.protocols = make(map[string]bool)
+            for _, protocol := range tt.protocols {
+                pf.protocols[protocol] = true
+            }
+            pf.filterSources()
+
+            kept := false
+            for _, source := range pf.sources {
+                if strings.HasPrefix(source.URL, geonodeAPI+"?") {
+                    kept = true
+                }
+            }
+            if kept != tt.want {
+                t.Errorf("geonode source kept = %v, want %v", kept, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+    "database/sql"
+    "log"
+    "net"
+    "strconv"
+    "time"
+
+    _ "modernc.org/sqlite"
+)
+
+const proxiesSchema = `
+CREATE TABLE IF NOT EXISTS proxies (
+    ip           TEXT    NOT NULL,
+    port         INTEGER NOT NULL,
+    protocol     TEXT    NOT NULL,
+    latency_ms   INTEGER NOT NULL,
+    anonymity    TEXT    NOT NULL DEFAULT '',
+    country      TEXT    NOT NULL DEFAULT '',
+    last_checked TEXT    NOT NULL,
+    alive        INTEGER NOT NULL,
+    PRIMARY KEY (ip, port, protocol)
+)`
+
+const upsertProxy = `
+INSERT INTO proxies (ip, port, protocol, latency_ms, anonymity, country, last_checked, alive)
+VALUES (?, ?, ?, ?, ?, ?, ?, 1)
+ON CONFLICT (ip, port, protocol) DO UPDATE SET
+    latency_ms   = excluded.latency_ms,
+    anonymity    = excluded.anonymity,
+    country      = excluded.country,
+    last_checked = excluded.last_checked,
+    alive        = 1`
+
+// updateDB saves the working proxies to the database at dbPath, if any
+func (pf *ProxyFetcher) updateDB(proxies []ProxyResult) {
+    if pf.dbPath == "" {
+        return
+    }
+    if err := saveToDB(pf.dbPath, proxies); err != nil {
+        log.Printf("Error saving proxies to %s: %v", pf.dbPath, err)
+    } else {
+        infof("Saved %d working proxies to %s", len(proxies), pf.dbPath)
+    }
+}
+
+// saveToDB upserts the working proxies into the SQLite database at path.
+// Proxies stored by earlier runs that are missing from this run are kept
+// but marked as no longer alive.
+func saveToDB(path string, proxies []ProxyResult) error {
+    db, err := sql.Open("sqlite", path)
+    if err != nil {
+        return err
+    }
+    defer db.Close()
+
+    if _, err := db.Exec(proxiesSchema); err != nil {
+        return err
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    if _, err := tx.Exec("UPDATE proxies SET alive = 0"); err != nil {
+        return err
+    }
+
+    stmt, err := tx.Prepare(upsertProxy)
+    if err != nil {
+        return err
+    }
+    defer stmt.Close()
+
+    for _, proxy := range proxies {
+        host, portStr, err := net.SplitHostPort(proxy.Proxy)
+        if err != nil {
+            continue
+        }
+        port, _ := strconv.Atoi(portStr)
+
+        _, err = stmt.Exec(host, port, proxy.Protocol, proxy.Latency.Milliseconds(),
+            proxy.Anonymity, proxy.Country, proxy.CheckedAt.UTC().Format(time.RFC3339))
+        if err != nil {
+            return err
+        }
+    }
+
+    return tx.Commit()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+    "database/sql"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// TestSaveProxiesMarksDBDead saves a working proxy and then a run with none,
+// and expects the database to no longer list it as alive
+func TestSaveProxiesMarksDBDead(t *testing.T) {
+    pf := NewProxyFetcher()
+    pf.outDir = t.TempDir()
+    pf.formats = map[string]bool{}
+    pf.noTelegram = true
+    pf.now = func() time.Time { return fixtureTime }
+    pf.dbPath = filepath.Join(pf.outDir, "proxies.db")
+
+    pf.saveProxies([]ProxyResult{{Proxy: "192.0.2.1:8080", ProxyInfo: ProxyInfo{Protocol: "http"}, CheckedAt: fixtureTime}})
+    pf.saveProxies(nil)
+
+    db, err := sql.Open("sqlite", pf.dbPath)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    var alive int
+    if err := db.QueryRow("SELECT alive FROM proxies WHERE ip = '192.0.2.1'").Scan(&alive); err != nil {
+        t.Fatal(err)
+    }
+    if alive != 0 {
+        t.Errorf("alive = %d after a run with no working proxies, want 0", alive)
+    }
+}
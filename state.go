@@ -0,0 +1,209 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+// ProxyHistory is the uptime record of a single proxy across runs
+type ProxyHistory struct {
+    Alive       int       `json:"alive"`
+    Dead        int       `json:"dead"`
+    LastChecked time.Time `json:"last_checked"`
+    // FirstChecked and LastAlive date the proxy for -max-age pruning
+    FirstChecked time.Time `json:"first_checked,omitzero"`
+    LastAlive    time.Time `json:"last_alive,omitzero"`
+    // Failures counts the dead checks since the proxy was last alive
+    Failures int `json:"failures,omitempty"`
+    // Protocol and Source let a failing proxy be re-checked in later runs
+    // even when no source lists it anymore
+    Protocol string `json:"protocol,omitempty"`
+    Source   string `json:"source,omitempty"`
+    // Authenticated marks proxies listed with credentials, which are not
+    // kept in the state file, so they are only re-checked when a source
+    // lists them again
+    Authenticated bool `json:"authenticated,omitempty"`
+}
+
+// SuccessRate is the fraction of checks in which the proxy was alive
+func (h *ProxyHistory) SuccessRate() float64 {
+    total := h.Alive + h.Dead
+    if total == 0 {
+        return 0
+    }
+    return float64(h.Alive) / float64(total)
+}
+
+// History tracks proxy uptime across runs and is persisted as a JSON file.
+// Proxies is keyed by protocol://host:port, like the fetcher's store, so an
+// endpoint listed under several protocols keeps one record per protocol.
+type History struct {
+    mu      sync.Mutex
+    path    string
+    Proxies map[string]*ProxyHistory `json:"proxies"`
+}
+
+// historyKey returns the Proxies key of a stored proxy
+func historyKey(key proxyKey) string {
+    return key.Protocol + "://" + key.Address
+}
+
+// parseHistoryKey is the inverse of historyKey
+func parseHistoryKey(s string) (proxyKey, bool) {
+    protocol, address, ok := strings.Cut(s, "://")
+    return proxyKey{protocol, address}, ok
+}
+
+// loadHistory reads the state file at path, starting an empty history if it
+// does not exist yet
+func loadHistory(path string) (*History, error) {
+    h := &History{path: path, Proxies: make(map[string]*ProxyHistory)}
+
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return h, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if err := json.Unmarshal(data, h); err != nil {
+        return nil, err
+    }
+    if h.Proxies == nil {
+        h.Proxies = make(map[string]*ProxyHistory)
+    }
+    // State files written before the protocol was part of the key use bare
+    // host:port keys
+    for proxy, entry := range h.Proxies {
+        if _, ok := parseHistoryKey(proxy); ok {
+            continue
+        }
+        protocol := entry.Protocol
+        if protocol == "" {
+            protocol = "http"
+        }
+        delete(h.Proxies, proxy)
+        key := historyKey(proxyKey{protocol, proxy})
+        if _, exists := h.Proxies[key]; !exists {
+            h.Proxies[key] = entry
+        }
+    }
+    return h, nil
+}
+
+// lastSeen is when the proxy was last alive or, if it never was, when it
+// was first checked
+func (e *ProxyHistory) lastSeen() time.Time {
+    switch {
+    case !e.LastAlive.IsZero():
+        return e.LastAlive
+    case !e.FirstChecked.IsZero():
+        return e.FirstChecked
+    default:
+        // Entries written before these dates were tracked
+        return e.LastChecked
+    }
+}
+
+// prune drops the proxies not seen alive within maxAge of now and returns
+// how many were dropped
+func (h *History) prune(maxAge time.Duration, now time.Time) int {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    dropped := 0
+    for proxy, entry := range h.Proxies {
+        if now.Sub(entry.lastSeen()) > maxAge {
+            delete(h.Proxies, proxy)
+            dropped++
+        }
+    }
+    return dropped
+}
+
+// record merges the outcome of a check into the proxy's history and returns
+// its number of consecutive failures
+func (h *History) record(key proxyKey, info ProxyInfo, alive bool, at time.Time) int {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    entry, ok := h.Proxies[historyKey(key)]
+    if !ok {
+        entry = &ProxyHistory{FirstChecked: at}
+        h.Proxies[historyKey(key)] = entry
+    }
+    if alive {
+        entry.Alive++
+        entry.Failures = 0
+        entry.LastAlive = at
+    } else {
+        entry.Dead++
+        entry.Failures++
+    }
+    entry.LastChecked = at
+    entry.Protocol = info.Protocol
+    entry.Source = info.Source
+    entry.Authenticated = info.Username != ""
+    return entry.Failures
+}
+
+// forget removes the proxy from the history
+func (h *History) forget(key proxyKey) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    delete(h.Proxies, historyKey(key))
+}
+
+// failing calls fn for every proxy that failed its last checks fewer than
+// max times in a row and can be checked without credentials
+func (h *History) failing(max int, fn func(key proxyKey, entry ProxyHistory)) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    for proxy, entry := range h.Proxies {
+        key, ok := parseHistoryKey(proxy)
+        if ok && entry.Failures > 0 && entry.Failures < max && !entry.Authenticated {
+            fn(key, *entry)
+        }
+    }
+}
+
+// successRate returns the proxy's success rate, or 0 if it was never checked
+func (h *History) successRate(key proxyKey) float64 {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    if entry, ok := h.Proxies[historyKey(key)]; ok {
+        return entry.SuccessRate()
+    }
+    return 0
+}
+
+// save writes the history back to its state file
+func (h *History) save() error {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    data, err := json.MarshalIndent(h, "", "  ")
+    if err != nil {
+        return err
+    }
+    return writeFileAtomic(h.path, append(data, '\n'), 0644)
+}
+
+// filterReliable keeps only the proxies whose success rate is at least min
+func filterReliable(proxies []ProxyResult, min float64) []ProxyResult {
+    var filtered []ProxyResult
+    for _, proxy := range proxies {
+        if proxy.SuccessRate >= min {
+            filtered = append(filtered, proxy)
+        }
+    }
+    return filtered
+}
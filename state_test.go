@@ -0,0 +1,86 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// TestHistoryKeyedByProtocol checks that an address checked under two
+// protocols keeps a record per protocol
+func TestHistoryKeyedByProtocol(t *testing.T) {
+    h := &History{Proxies: make(map[string]*ProxyHistory)}
+    at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    httpKey := proxyKey{"http", "192.0.2.1:1080"}
+    socksKey := proxyKey{"socks5", "192.0.2.1:1080"}
+
+    h.record(httpKey, ProxyInfo{Protocol: "http"}, false, at)
+    h.record(socksKey, ProxyInfo{Protocol: "socks5"}, true, at)
+
+    if rate := h.successRate(httpKey); rate != 0 {
+        t.Errorf("http success rate = %v, want 0", rate)
+    }
+    if rate := h.successRate(socksKey); rate != 1 {
+        t.Errorf("socks5 success rate = %v, want 1", rate)
+    }
+
+    var failing []proxyKey
+    h.failing(3, func(key proxyKey, _ ProxyHistory) {
+        failing = append(failing, key)
+    })
+    if len(failing) != 1 || failing[0] != httpKey {
+        t.Errorf("failing = %v, want [%v]", failing, httpKey)
+    }
+
+    h.forget(httpKey)
+    if _, ok := h.Proxies[historyKey(socksKey)]; !ok || len(h.Proxies) != 1 {
+        t.Errorf("forget(%v) left %v", httpKey, h.Proxies)
+    }
+}
+
+// TestLoadHistoryMigratesBareKeys checks that state files keyed by host:port
+// are rekeyed by protocol on load
+func TestLoadHistoryMigratesBareKeys(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "state.json")
+    state := `{"proxies": {
+        "192.0.2.1:1080": {"alive": 1, "protocol": "socks5"},
+        "192.0.2.2:8080": {"alive": 1}
+    }}`
+    if err := os.WriteFile(path, []byte(state), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    h, err := loadHistory(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, key := range []string{"socks5://192.0.2.1:1080", "http://192.0.2.2:8080"} {
+        if _, ok := h.Proxies[key]; !ok {
+            t.Errorf("%s missing from %v", key, h.Proxies)
+        }
+    }
+    if len(h.Proxies) != 2 {
+        t.Errorf("loaded %d proxies, want 2", len(h.Proxies))
+    }
+}
+
+// TestFailingSkipsAuthenticated checks that proxies listed with
+// credentials, which the state file does not keep, are not restored
+func TestFailingSkipsAuthenticated(t *testing.T) {
+    h := &History{Proxies: make(map[string]*ProxyHistory)}
+    at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    plain := proxyKey{"http", "192.0.2.1:8080"}
+    auth := proxyKey{"http", "192.0.2.2:8080"}
+
+    h.record(plain, ProxyInfo{Protocol: "http"}, false, at)
+    h.record(auth, ProxyInfo{Protocol: "http", Username: "user", Password: "secret"}, false, at)
+
+    var failing []proxyKey
+    h.failing(3, func(key proxyKey, _ ProxyHistory) {
+        failing = append(failing, key)
+    })
+    if len(failing) != 1 || failing[0] != plain {
+        t.Errorf("failing = %v, want [%v]", failing, plain)
+    }
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "text/tabwriter"
+    "time"
+)
+
+// SourceStats counts the proxies a source contributed and how many of them
+// turned out to work
+type SourceStats struct {
+    Fetched int `json:"fetched"`
+    Valid   int `json:"valid"`
+}
+
+// sourceStats counts the stored proxies per source. It must run before
+// checkAndFilterProxies, which forgets dead proxies.
+func (pf *ProxyFetcher) sourceStats() map[string]*SourceStats {
+    stats := make(map[string]*SourceStats)
+    for _, source := range pf.sources {
+        stats[source.URL] = &SourceStats{}
+    }
+
+    pf.eachProxy(func(_ proxyKey, info ProxyInfo) bool {
+        source := info.Source
+        if stats[source] == nil {
+            stats[source] = &SourceStats{}
+        }
+        stats[source].Fetched++
+        return true
+    })
+    return stats
+}
+
+// countValid attributes each working proxy to the source it came from
+func countValid(stats map[string]*SourceStats, proxies []ProxyResult) {
+    for _, proxy := range proxies {
+        if stats[proxy.Source] == nil {
+            stats[proxy.Source] = &SourceStats{}
+        }
+        stats[proxy.Source].Valid++
+    }
+}
+
+// logSourceStats logs a table of the per-source counts
+func logSourceStats(stats map[string]*SourceStats) {
+    sources := make([]string, 0, len(stats))
+    for source := range stats {
+        sources = append(sources, source)
+    }
+    sort.Strings(sources)
+
+    var buf bytes.Buffer
+    w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "SOURCE\tFETCHED\tVALID\tRATE")
+    for _, source := range sources {
+        s := stats[source]
+        rate := 0.0
+        if s.Fetched > 0 {
+            rate = float64(s.Valid) / float64(s.Fetched) * 100
+        }
+        fmt.Fprintf(w, "%s\t%d\t%d\t%.1f%%\n", source, s.Fetched, s.Valid, rate)
+    }
+    w.Flush()
+
+    infof("Per-source summary:\n%s", buf.String())
+}
+
+// printFetchStats writes the per-source fetch counts and the number of unique
+// proxies to stdout, for -dry-run
+func printFetchStats(stats map[string]*SourceStats) {
+    unique := 0
+    sources := make([]string, 0, len(stats))
+    for source, s := range stats {
+        sources = append(sources, source)
+        unique += s.Fetched
+    }
+    sort.Strings(sources)
+
+    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "SOURCE\tPROXIES")
+    for _, source := range sources {
+        fmt.Fprintf(w, "%s\t%d\n", source, stats[source].Fetched)
+    }
+    w.Flush()
+    fmt.Printf("Total unique proxies: %d\n", unique)
+}
+
+// RunSummary describes the outcome of one cycle for dashboards and scripts
+type RunSummary struct {
+    Timestamp       time.Time               `json:"timestamp"`
+    DurationSeconds float64                 `json:"duration_seconds"`
+    Fetched         int                     `json:"fetched"`
+    Valid           int                     `json:"valid"` // working proxies before any output filter
+    Saved           int                     `json:"saved"` // proxies written to the outputs
+    Protocols       map[string]int          `json:"protocols"`
+    Sources         map[string]*SourceStats `json:"sources"`
+}
+
+// newRunSummary builds the summary of a cycle that started at start, from
+// the per-source stats and the proxies that were saved
+func newRunSummary(start time.Time, stats map[string]*SourceStats, saved []ProxyResult) RunSummary {
+    summary := RunSummary{
+        Timestamp:       time.Now(),
+        DurationSeconds: time.Since(start).Seconds(),
+        Saved:           len(saved),
+        Protocols:       make(map[string]int),
+        Sources:         stats,
+    }
+    for _, s := range stats {
+        summary.Fetched += s.Fetched
+        summary.Valid += s.Valid
+    }
+    for _, proxy := range saved {
+        summary.Protocols[proxy.Protocol]++
+    }
+    return summary
+}
+
+// writeSummary writes the run summary to path as indented JSON
+func writeSummary(path string, summary RunSummary) error {
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+    data, err := json.MarshalIndent(summary, "", "  ")
+    if err != nil {
+        return err
+    }
+    return writeFileAtomic(path, append(data, '\n'), 0644)
+}
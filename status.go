@@ -0,0 +1,57 @@
+package main
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// statusSet is a set of acceptable HTTP status codes, given as codes such as
+// 204 or classes such as 2xx
+type statusSet struct {
+    codes   map[int]bool
+    classes map[int]bool // 2 for 2xx and so on
+}
+
+// parseStatusSet reads a comma-separated list like "200,204,3xx"
+func parseStatusSet(value string) (statusSet, error) {
+    set := statusSet{codes: make(map[int]bool), classes: make(map[int]bool)}
+    for _, item := range strings.Split(value, ",") {
+        item = strings.ToLower(strings.TrimSpace(item))
+        if item == "" {
+            continue
+        }
+        if len(item) == 3 && strings.HasSuffix(item, "xx") && item[0] >= '1' && item[0] <= '5' {
+            set.classes[int(item[0]-'0')] = true
+            continue
+        }
+        code, err := strconv.Atoi(item)
+        if err != nil || code < 100 || code > 599 {
+            return set, fmt.Errorf("invalid status code %q", item)
+        }
+        set.codes[code] = true
+    }
+    if len(set.codes) == 0 && len(set.classes) == 0 {
+        return set, fmt.Errorf("no status codes given")
+    }
+    return set, nil
+}
+
+// contains reports whether code is acceptable
+func (s statusSet) contains(code int) bool {
+    return s.codes[code] || s.classes[code/100]
+}
+
+// acceptsRedirects reports whether any 3xx code is acceptable, in which case
+// redirects must be returned rather than followed
+func (s statusSet) acceptsRedirects() bool {
+    if s.classes[3] {
+        return true
+    }
+    for code := range s.codes {
+        if code/100 == 3 {
+            return true
+        }
+    }
+    return false
+}
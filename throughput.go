@@ -0,0 +1,59 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+)
+
+// maxThroughputBytes caps how much of the throughput payload is downloaded
+const maxThroughputBytes = 10 << 20
+
+// measureThroughput downloads throughputURL through the proxy and returns
+// the transfer rate of the response body in bytes per second
+func (pf *ProxyFetcher) measureThroughput(ctx context.Context, proxy string, info ProxyInfo) (float64, error) {
+    client, err := pf.proxyClient(proxy, info, pf.throughputTimeout)
+    if err != nil {
+        return 0, err
+    }
+    defer client.CloseIdleConnections()
+
+    req, err := http.NewRequestWithContext(ctx, "GET", pf.throughputURL, nil)
+    if err != nil {
+        return 0, err
+    }
+    resp, err := client.Do(req)
+    if err != nil {
+        return 0, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return 0, fmt.Errorf("status code: %d", resp.StatusCode)
+    }
+
+    start := time.Now()
+    n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, maxThroughputBytes))
+    if err != nil {
+        return 0, err
+    }
+    elapsed := time.Since(start)
+    if n == 0 || elapsed <= 0 {
+        return 0, fmt.Errorf("empty payload")
+    }
+    return float64(n) / elapsed.Seconds(), nil
+}
+
+// filterThroughput keeps the proxies that downloaded at least min bytes per
+// second
+func filterThroughput(proxies []ProxyResult, min float64) []ProxyResult {
+    var filtered []ProxyResult
+    for _, proxy := range proxies {
+        if proxy.Throughput >= min {
+            filtered = append(filtered, proxy)
+        }
+    }
+    return filtered
+}
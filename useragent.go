@@ -0,0 +1,88 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "math/rand"
+    "net/http"
+    "net/url"
+    "strings"
+)
+
+// defaultUserAgent is sent to sources unless another one is configured
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+// userAgents are picked from at random by -random-user-agent
+var userAgents = []string{
+    defaultUserAgent,
+    "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+    "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+    "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+    "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+    "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+}
+
+// setFetchHeaders sets the User-Agent and extra headers of a source request
+func (pf *ProxyFetcher) setFetchHeaders(req *http.Request) {
+    userAgent := pf.userAgent
+    if pf.randomUserAgent {
+        userAgent = userAgents[rand.Intn(len(userAgents))]
+    }
+    req.Header.Set("User-Agent", userAgent)
+    for name, values := range pf.fetchHeaders {
+        req.Header[name] = values
+    }
+}
+
+// setSourceAuth adds the headers and query parameters configured for the
+// source, such as API keys, to req. They take precedence over the global
+// fetch headers.
+func setSourceAuth(req *http.Request, source Source) {
+    for name, value := range source.Headers {
+        req.Header.Set(name, value)
+    }
+    if len(source.Query) > 0 {
+        query := req.URL.Query()
+        for name, value := range source.Query {
+            query.Set(name, value)
+        }
+        req.URL.RawQuery = query.Encode()
+    }
+}
+
+// redactURL replaces the URL in a request error, which includes any query
+// parameters added by setSourceAuth, with the configured one so secrets do
+// not end up in logs
+func redactURL(err error, rawURL string) error {
+    var urlErr *url.Error
+    if errors.As(err, &urlErr) {
+        redacted := *urlErr
+        redacted.URL = rawURL
+        return &redacted
+    }
+    return err
+}
+
+// headerList is a flag.Value collecting repeated "Name: value" headers.
+// Unlike stringList it does not split on commas, which header values may
+// contain.
+type headerList http.Header
+
+func (h headerList) String() string {
+    var pairs []string
+    for name, values := range h {
+        for _, v := range values {
+            pairs = append(pairs, name+": "+v)
+        }
+    }
+    return strings.Join(pairs, ", ")
+}
+
+func (h headerList) Set(value string) error {
+    name, v, ok := strings.Cut(value, ":")
+    if !ok || strings.TrimSpace(name) == "" {
+        return fmt.Errorf("header %q is not in Name: value form", value)
+    }
+    http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(v))
+    return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "text/template"
+    "time"
+)
+
+// defaultWebhookTemplate is the body posted by the generic webhook when no
+// template file is given
+const defaultWebhookTemplate = `{"timestamp": {{json .Timestamp}}, "count": {{.Count}}, "protocols": {{json .Protocols}}, "proxies": {{json .Proxies}}}`
+
+// webhook posts the working proxies to an arbitrary URL, rendering the body
+// from a text/template
+type webhook struct {
+    url     string
+    tmpl    *template.Template
+    headers http.Header
+}
+
+// webhookData is the value the webhook template is executed with
+type webhookData struct {
+    Timestamp time.Time
+    Count     int
+    Protocols map[string]int
+    Proxies   []proxyRecord
+    Lines     []string // proxychains lines, without credentials
+}
+
+// newWebhook parses the template at templatePath, or the default JSON
+// template when the path is empty
+func newWebhook(url, templatePath string, headers http.Header) (*webhook, error) {
+    text := defaultWebhookTemplate
+    if templatePath != "" {
+        data, err := os.ReadFile(templatePath)
+        if err != nil {
+            return nil, err
+        }
+        text = string(data)
+    }
+
+    tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+        "json": func(v interface{}) (string, error) {
+            data, err := json.Marshal(v)
+            return string(data), err
+        },
+    }).Parse(text)
+    if err != nil {
+        return nil, err
+    }
+    return &webhook{url: url, tmpl: tmpl, headers: headers}, nil
+}
+
+// send renders the template for the proxies and posts it
+func (w *webhook) send(proxies []ProxyResult) error {
+    data := webhookData{
+        Timestamp: time.Now(),
+        Count:     len(proxies),
+        Protocols: make(map[string]int),
+    }
+    for _, proxy := range proxies {
+        data.Protocols[proxy.Protocol]++
+        data.Proxies = append(data.Proxies, newProxyRecord(proxy))
+        if line, ok := proxychainsLine(proxy, false); ok {
+            data.Lines = append(data.Lines, line)
+        }
+    }
+
+    var body bytes.Buffer
+    if err := w.tmpl.Execute(&body, data); err != nil {
+        return fmt.Errorf("rendering webhook template: %v", err)
+    }
+
+    req, err := http.NewRequest("POST", w.url, &body)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    for name, values := range w.headers {
+        req.Header[name] = values
+    }
+
+    client := &http.Client{Timeout: 30 * time.Second}
+    resp, err := client.Do(req)
+    if err != nil {
+        return fmt.Errorf("failed to send webhook: %v", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        body, _ := io.ReadAll(resp.Body)
+        return fmt.Errorf("webhook error: status %d, response: %s", resp.StatusCode, string(body))
+    }
+
+    infof("Sent %d proxies to webhook", len(proxies))
+    return nil
+}